@@ -0,0 +1,40 @@
+package main
+
+import (
+	"log"
+)
+
+// 播报方式
+const (
+	announceOff = ""
+	announceLog = "log"
+	announceTTS = "tts"
+)
+
+func isValidAnnounceMode(mode string) bool {
+	switch mode {
+	case announceOff, announceLog, announceTTS:
+		return true
+	}
+	return false
+}
+
+// announce 按配置的方式播报一条消息，语音播报在后台进行，不影响计时
+func announce(mode, msg string) {
+	switch mode {
+	case announceLog:
+		progress("announce", 0, "    > %s", msg)
+	case announceTTS:
+		progress("announce", 0, "    > %s", msg)
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("语音播报崩溃: %v", r)
+				}
+			}()
+			if err := speak(msg); err != nil {
+				log.Printf("语音播报失败: %v", err)
+			}
+		}()
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// 当天累计的专注时间（本次运行内），日期变化时清零
+	dailyFocusMu   sync.Mutex
+	dailyFocusDay  string
+	dailyFocus     time.Duration
+	budgetNotified bool
+
+	// 本次运行累计的专注时间（纳秒），跨中循环和大循环累加，重启后从 0 开始
+	totalFocusNano int64
+)
+
+// countDailyFocus 在专注阶段结束时累计当天和本次运行的专注时间，其他阶段忽略；
+// 用完每日专注预算时提示一次
+func countDailyFocus(phase string, actual time.Duration) {
+	if phase != phaseMicroFocus && phase != phaseQuickFocus {
+		return
+	}
+	atomic.AddInt64(&totalFocusNano, int64(actual))
+	now := time.Now()
+
+	dailyFocusMu.Lock()
+	rollDailyFocus(now)
+	dailyFocus += actual
+	exhausted := currentConfig().DailyFocusBudgetM > 0 && !budgetNotified && dailyFocus >= dailyFocusBudget()
+	if exhausted {
+		budgetNotified = true
+	}
+	dailyFocusMu.Unlock()
+
+	if exhausted {
+		msg := fmt.Sprintf("今天的专注预算 %v 已用完", dailyFocusBudget())
+		progress("daily_budget_exhausted", 0, "    > %s", msg)
+		log.Println(msg)
+	}
+}
+
+// rollDailyFocus 在日期变化时清零累计，调用方需持有 dailyFocusMu
+func rollDailyFocus(now time.Time) {
+	day := now.Format("2006-01-02")
+	if day != dailyFocusDay {
+		dailyFocusDay = day
+		dailyFocus = 0
+		budgetNotified = false
+	}
+}
+
+func dailyFocusBudget() time.Duration {
+	return time.Duration(currentConfig().DailyFocusBudgetM) * time.Minute
+}
+
+// dailyBudgetRemaining 返回今天剩余的专注预算，不小于 0；没有配置预算时 ok 为 false
+func dailyBudgetRemaining(now time.Time) (remaining time.Duration, ok bool) {
+	if currentConfig().DailyFocusBudgetM <= 0 {
+		return 0, false
+	}
+	dailyFocusMu.Lock()
+	rollDailyFocus(now)
+	remaining = dailyFocusBudget() - dailyFocus
+	dailyFocusMu.Unlock()
+
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true
+}
+
+// dailyFocusTotal 返回今天累计的专注时间
+func dailyFocusTotal(now time.Time) time.Duration {
+	dailyFocusMu.Lock()
+	defer dailyFocusMu.Unlock()
+	rollDailyFocus(now)
+	return dailyFocus
+}
+
+// announceDailyFocus 在中循环结束时按配置播报今天累计的专注时间
+func announceDailyFocus() {
+	cfg := currentConfig()
+	if cfg.AnnounceDailyFocus == announceOff {
+		return
+	}
+	m := int(dailyFocusTotal(time.Now()) / time.Minute)
+	msg := fmt.Sprintf("今天已专注 %d 分钟", m)
+	if m >= 60 {
+		msg = fmt.Sprintf("今天已专注 %d 小时 %d 分钟", m/60, m%60)
+	}
+	announce(cfg.AnnounceDailyFocus, msg)
+}
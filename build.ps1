@@ -5,6 +5,13 @@ Remove-Item -Path "dist" -Recurse -Force -ErrorAction SilentlyContinue
 # 创建发布目录
 New-Item -ItemType Directory -Force -Path "dist" | Out-Null
 
+# 版本信息，通过 -ldflags -X 注入到 main.version 等变量
+$Version = git describe --tags --always --dirty 2>$null
+if (-not $Version) { $Version = "dev" }
+$Commit = git rev-parse --short HEAD 2>$null
+$BuildDate = Get-Date -Format "yyyy-MM-ddTHH:mm:ssK"
+$VersionFlags = "-X main.version=$Version -X main.commit=$Commit -X main.buildDate=$BuildDate"
+
 # 定义通用函数来打包
 function Package-Variant {
     param (
@@ -15,6 +22,7 @@ function Package-Variant {
     )
 
     Write-Host "正在构建: $VariantName ($ExeName)..." -ForegroundColor Cyan
+    $LdFlags = "$LdFlags $VersionFlags"
     
     # 编译
     if ($BuildTags) {
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+const (
+	defaultChartWidth = 60
+	minChartWidth     = 20
+	maxChartWidth     = 400
+
+	// chartSeed 固定图表的随机种子，同一份配置每次画出的计划相同
+	chartSeed = 1
+)
+
+// 图表中各阶段使用的字符
+const (
+	chartFocus     = '█'
+	chartMicroRest = '·'
+	chartMesoRest  = '░'
+)
+
+type chartSpan struct {
+	d    time.Duration
+	mark rune
+}
+
+// renderScheduleChart 把一个大循环的计划画成文本甘特图：每个中循环一行，
+// 专注、小休息和中循环休息按时长比例显示，底部是时间轴。
+// 实际运行时每个中循环的时长是随机的，图表只是同一配置下的一份示例
+func renderScheduleChart(width int) string {
+	cfg := currentConfig()
+	if width < minChartWidth {
+		width = minChartWidth
+	}
+	if width > maxChartWidth {
+		width = maxChartWidth
+	}
+	rng := rand.New(rand.NewSource(chartSeed))
+
+	rows := make([][]chartSpan, cfg.MesoCount)
+	longest := time.Duration(0)
+	for m := range rows {
+		micros := planMesoSchedule(cfg, mesoTargetDuration(cfg, m == len(rows)-1), rng.Intn)
+		for i, d := range micros {
+			rows[m] = append(rows[m], chartSpan{d, chartFocus})
+			if restAfterMicro(cfg, i, len(micros)) {
+				rows[m] = append(rows[m], chartSpan{cfg.microRest(), chartMicroRest})
+			}
+		}
+		if m < len(rows)-1 {
+			rows[m] = append(rows[m], chartSpan{cfg.mesoRest(), chartMesoRest})
+		}
+
+		total := time.Duration(0)
+		for _, sp := range rows[m] {
+			total += sp.d
+		}
+		if total > longest {
+			longest = total
+		}
+	}
+	if longest <= 0 {
+		return ""
+	}
+	scale := float64(width) / float64(longest)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "一个大循环的计划（%s 专注  %s 小休息  %s 中循环休息）\n\n",
+		string(chartFocus), string(chartMicroRest), string(chartMesoRest))
+	for m, row := range rows {
+		fmt.Fprintf(&b, "中循环%2d │", m+1)
+		// 按累计时长换算位置，避免逐段取整造成误差累积；
+		// 每段至少占一格，否则短的小休息会消失，相邻的专注连成一片
+		elapsed, col := time.Duration(0), 0
+		for _, sp := range row {
+			elapsed += sp.d
+			end := int(float64(elapsed)*scale + 0.5)
+			if end <= col {
+				end = col + 1
+			}
+			b.WriteString(strings.Repeat(string(sp.mark), end-col))
+			col = end
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(chartAxis(width, longest))
+	// 只有一个大循环时没有大循环休息
+	if cfg.MacroCount != 1 {
+		fmt.Fprintf(&b, "\n之后是大循环休息 %v\n", cfg.macroRest())
+	}
+	return b.String()
+}
+
+// chartAxis 画时间轴：刻度间隔取能让标签不重叠的最小整分钟数
+func chartAxis(width int, total time.Duration) string {
+	const indent = "          " // 与 "中循环 1 │" 的显示宽度一致
+	step := time.Minute
+	for _, m := range []int{1, 2, 5, 10, 15, 30, 60, 120} {
+		step = time.Duration(m) * time.Minute
+		if int(total/step) <= width/8 {
+			break
+		}
+	}
+
+	axis := []rune(strings.Repeat("─", width+1))
+	labels := []rune(strings.Repeat(" ", width+8))
+	scale := float64(width) / float64(total)
+	for t := time.Duration(0); t <= total; t += step {
+		pos := int(float64(t)*scale + 0.5)
+		axis[pos] = '┼'
+		label := fmt.Sprintf("%dm", int(t.Minutes()))
+		copy(labels[pos:], []rune(label))
+	}
+	return indent + "└" + string(axis[1:]) + "\n" + indent + strings.TrimRight(string(labels), " ") + "\n"
+}
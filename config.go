@@ -0,0 +1,748 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Config 保存番茄钟的配置信息
+type Config struct {
+	MicroBaseS     int `json:"小循环基础时间秒"`
+	MicroOffsetS   int `json:"小循环随机偏移秒"`
+	MicroRestS     int `json:"小循环休息时间秒"`
+	MicroRestEvery int `json:"小循环休息间隔"` // 每完成几个小循环休息一次，默认每个都休息
+
+	// 小循环随机偏移超过基础时间的这个百分比时，启动时提示时长波动过大（只提示，不阻止运行）；负数表示不提示
+	MicroOffsetWarnPct int `json:"小循环随机偏移提示百分比"`
+
+	// 生成小循环时长的随机种子，非 0 时每次运行得到相同的时间安排，便于复现问题；0 表示按启动时间随机
+	RandomSeed int64 `json:"随机种子"`
+
+	// 每个中循环内最多跳过几次（任何方式的跳过都计入），0 表示不限制
+	MaxSkipsPerMeso int `json:"每个中循环最多跳过"`
+
+	// 每天计划专注的分钟数（小循环专注和快速专注都计入），用于显示今天剩余的预算，
+	// 用完时提示一次，不会停止计时；0 表示不设预算
+	DailyFocusBudgetM int `json:"每日专注预算分"`
+
+	// 把本中循环内跳过的专注时间合并成一个补回小循环，在中循环休息前进行，最长 补回上限秒
+	MakeupSkipped bool `json:"补回跳过时间"`
+	MakeupMaxS    int  `json:"补回上限秒"`
+
+	// 小休息开始时，如果这么多秒内有键盘鼠标输入，就跳过这次休息（仅 Windows，0 关闭）；
+	// 不连续跳过，每个中循环最多跳过 活跃跳过小休息上限 次
+	ActiveSkipRestS   int `json:"活跃时跳过小休息秒"`
+	ActiveSkipRestMax int `json:"活跃跳过小休息上限"`
+
+	MesoDurationM int `json:"中循环总时间分"`
+	MesoRestM     int `json:"中循环休息时间分"`
+	MesoCount     int `json:"中循环组数"`
+	MacroRestM    int `json:"大循环休息时间分"`
+	MacroCount    int `json:"大循环次数"` // 0 表示无限循环
+	Port          int `json:"端口"`
+
+	// Web 服务监听的地址，默认 0.0.0.0 可以从局域网访问；只在本机使用时可以填 127.0.0.1
+	BindAddress string `json:"Web监听地址"`
+
+	// 进程运行这么多小时后，不论处于哪个阶段都结束计时并退出，避免忘记关闭的实例一直运行；0 表示不限制
+	MaxRuntimeH int `json:"最长运行小时"`
+
+	// 自适应中循环休息：中循环内没有跳过小循环时休息缩短 步长百分比，每跳过一次延长 步长百分比，
+	// 结果限制在中循环休息时间的 下限百分比 到 上限百分比 之间；默认关闭，使用固定休息
+	AdaptiveMesoRest        bool `json:"自适应中循环休息"`
+	AdaptiveMesoRestStepPct int  `json:"自适应中循环休息步长百分比"`
+	AdaptiveMesoRestMinPct  int  `json:"自适应中循环休息下限百分比"`
+	AdaptiveMesoRestMaxPct  int  `json:"自适应中循环休息上限百分比"`
+
+	// 带单位的时长写法（如 "90s"、"25m"、"1h30m"），填写时优先于上面的整数字段
+	MicroBaseD    Duration `json:"小循环基础时间"`
+	MicroOffsetD  Duration `json:"小循环随机偏移"`
+	MicroRestD    Duration `json:"小循环休息时间"`
+	MesoDurationD Duration `json:"中循环总时间"`
+	MesoRestD     Duration `json:"中循环休息时间"`
+	MacroRestD    Duration `json:"大循环休息时间"`
+
+	// 大循环休息之后额外的间隔，结束后自动开始下一个大循环；需要确认时则等待确认再开始
+	MacroGapM   int      `json:"大循环间隔分"`
+	MacroGapD   Duration `json:"大循环间隔"`
+	MacroGapAck bool     `json:"大循环间隔需确认"`
+
+	// 小循环休息和中循环休息结束后停在 ready 阶段，确认回到座位（POST /control/ack 或跳过）后才开始下一个小循环；
+	// 超过 就绪确认超时秒 自动开始，负数表示一直等待
+	ReadyCheck         bool `json:"休息后确认就绪"`
+	ReadyCheckTimeoutS int  `json:"就绪确认超时秒"`
+
+	// 目标会话时长：填写后按它推算中循环组数，代替 中循环组数；有限次大循环时是整个会话的时长，
+	// 无限循环时是每个大循环的时长
+	TargetSessionM int      `json:"目标会话时长分"`
+	TargetSessionD Duration `json:"目标会话时长"`
+
+	// 按星期覆盖时间安排，键为 周一…周日 或 monday…sunday，值中只填需要改变的计时字段
+	Weekdays map[string]ScheduleOverride `json:"按星期"`
+
+	// 命名的时间安排（如 "深度工作"、"学习"），格式同 按星期；当前预设 叠加在基础配置上，按星期再叠加在预设上
+	Presets      map[string]ScheduleOverride `json:"预设"`
+	ActivePreset string                      `json:"当前预设"`
+
+	// 最后一个中循环结束后、大循环休息开始前的过渡时间
+	FinalMesoPauseS int `json:"大循环过渡秒"`
+
+	// 按事件类别设置终端输出的详细程度，键为 micro、meso、macro 等类别，值为 "info"、"warn" 或 "off"；
+	// 没有列出的类别为 "info"，错误总是输出
+	LogLevels map[string]string `json:"日志级别"`
+
+	// 每个小循环开始前播报它的时长: "" 关闭, "log" 输出到终端, "tts" 语音播报
+	AnnounceMicro string `json:"小循环预告"`
+
+	// 每个中循环结束时播报今天累计的专注时间，方式同 小循环预告
+	AnnounceDailyFocus string `json:"中循环结束播报今日专注"`
+
+	// 阶段切换时弹出系统通知，窗口隐藏时也能看到；发送失败只记录日志
+	Notifications bool `json:"系统通知"`
+
+	// 提示音与计时的关系: "overlap" 后台播放、下一阶段立即开始（默认），"pause" 播完再开始下一阶段
+	SoundTiming string `json:"提示音计时"`
+
+	// 提示音主题：主题目录下的每个子目录是一套提示音，文件以事件命名（如 micro_end.mp3）；
+	// 主题为空时使用 Sounds 目录下的默认提示音
+	SoundTheme     string `json:"提示音主题"`
+	SoundThemesDir string `json:"提示音主题目录"`
+
+	// 按事件指定提示音文件，键为事件名（如 "micro_end"），优先于提示音主题和默认提示音；
+	// 没有列出的事件仍使用原来的提示音
+	Sounds map[string]string `json:"事件提示音"`
+
+	// 提示音音量，0.0（静音）到 1.0（原始音量），超出范围时取最近的值；不填为 1.0
+	Volume *volumeLevel `json:"音量"`
+
+	// 同一事件的提示音在这段时间内只播放一次，0 或负数表示不限制；不填为 2000
+	SoundCooldownMs *int `json:"提示音冷却毫秒"`
+
+	// 提示音在阶段切换后随机延迟 0 到该毫秒数再播放，避免每次都在同一时刻响起而被习惯性忽略；
+	// 延迟的提示音在后台播放，阶段本身的计时不变。0 表示不延迟
+	SoundJitterMs int `json:"提示音随机延迟毫秒"`
+
+	// 计时器循环卡死（超过当前阶段结束时间加余量仍没有心跳）时的处理:
+	// "" 关闭, "log" 只记录, "restart" 重新开始计时循环, "exit" 以退出码 3 退出交给守护进程重启
+	WatchdogAction  string `json:"卡死处理"`
+	WatchdogMarginS int    `json:"卡死判定余量秒"`
+
+	// 随机会话 ID，写入日志行、-log-json 输出、历史记录、Webhook 和 /status，便于关联同一次运行的记录:
+	// "" 关闭, "process" 每次启动生成, "macro" 每个大循环重新生成
+	SessionIDMode string `json:"会话ID"`
+
+	// 每个阶段结束时输出计划时长和实际时长，并在窗口标题、Web 页面和 /status 中显示
+	ShowCycleTiming bool `json:"显示计划与实际时长"`
+
+	Debug bool `json:"调试"` // 开启调试接口（如 /debug/resources、/debug/plan）和规划细节输出
+
+	// Web 中间件：请求日志和按 IP 的每秒请求上限（0 表示不限制）
+	WebRequestLog bool `json:"Web请求日志"`
+	WebRateLimit  int  `json:"Web每秒请求上限"`
+
+	// 允许跨域访问的来源（如 "https://overlay.example.com"），"*" 表示任意来源；
+	// 不填时允许任意来源，填空列表 [] 时不发送跨域响应头
+	WebAllowOrigins []string `json:"Web允许来源"`
+
+	// 同时设置时，所有 Web 接口都需要 HTTP Basic 认证，避免局域网内的其他人操作计时；留空时不需要认证
+	WebUsername string `json:"Web用户名"`
+	WebPassword string `json:"Web密码"`
+
+	// /status 的时间取整到秒，减少轮询时的小数抖动；请求带 ?raw=1 时仍返回原始小数
+	WebStatusWholeSeconds bool `json:"Web状态取整秒"`
+
+	// 保存最近这么多条日志，通过 /logs 查看（需要 Web日志令牌），便于在浏览器中排查无界面运行的实例；0 表示关闭
+	WebLogLines int    `json:"Web日志条数"`
+	WebLogToken string `json:"Web日志令牌"`
+
+	// 每次切换阶段时向该 UDP 地址（如 "127.0.0.1:9000"）发送 OSC 消息，参数为阶段名和剩余秒数
+	OSCAddress string `json:"OSC地址"`
+	OSCPath    string `json:"OSC路径"`
+
+	// 每个事件发布一条 JSON 消息到该 MQTT 服务器（如 "192.168.1.10:1883"）的主题，
+	// 供手机、手表等伴侣应用振动提醒；为空时关闭
+	MQTTAddress  string `json:"MQTT地址"`
+	MQTTTopic    string `json:"MQTT主题"`
+	MQTTUsername string `json:"MQTT用户名"`
+	MQTTPassword string `json:"MQTT密码"`
+
+	// 每次事件 POST 到该地址；事件列表为空时发送全部事件
+	WebhookURL    string   `json:"Webhook地址"`
+	WebhookEvents []string `json:"Webhook事件"`
+
+	// Webhook 失败重试：重试次数、首次重试间隔（之后每次翻倍）、单次超时
+	WebhookRetries   int `json:"Webhook重试次数"`
+	WebhookBackoffMs int `json:"Webhook重试间隔毫秒"`
+	WebhookTimeoutS  int `json:"Webhook超时秒"`
+
+	// 重试后仍失败的 Webhook 记录到该文件（JSON Lines），超过上限时轮换
+	WebhookDeadLetter      string `json:"Webhook失败记录文件"`
+	WebhookDeadLetterMaxKB int    `json:"Webhook失败记录上限KB"`
+
+	// 每个阶段结束时把阶段、计划时长、实际时长和是否跳过追加到该 JSON Lines 文件，为空时不记录
+	HistoryFile string `json:"历史记录文件"`
+
+	// 音频初始化成功后播放的提示音，可用来确认声音正常；为空时不播放
+	StartupSound string `json:"启动提示音"`
+
+	// 每个中循环进行到一半时播放的提示音；为空时不播放
+	MesoHalfSound string `json:"中循环过半提示音"`
+
+	// -cooldown 冷却休息开始时播放的提示音，可以选一段舒缓的声音；为空时不播放
+	CooldownSound string `json:"冷却提示音"`
+
+	// Web 版：通过 /events 推送需要播放的提示音，由浏览器在用户点击页面后自行播放，
+	// 用于远程的 OBS 挂件或其他电脑上的页面
+	WebClientSound bool `json:"Web客户端提示音"`
+
+	// 完成全部大循环后的收尾提示
+	SessionEndSound   string `json:"结束提示音"`
+	SessionEndMessage string `json:"结束提示语"`
+	KeepWindowOpen    bool   `json:"结束后保留窗口"` // 窗口版：结束后显示总结，直到手动关闭窗口
+
+	ShowStreak bool `json:"窗口显示连续专注"` // 窗口版：在进度条上显示本次大循环内未跳过的小循环数
+
+	// 窗口版：专注时窗口连续失去焦点这么多秒后自动暂停，窗口重新获得焦点时继续；0 表示关闭
+	PauseOnBlurS int `json:"失去焦点暂停秒"`
+
+	// 窗口版：窗口始终显示在其他窗口之上，适合作为专注时的悬浮挂件
+	AlwaysOnTop bool `json:"窗口置顶"`
+
+	// 窗口版：在标题中显示按当前配置估算的会话总时长，无限循环时显示每个大循环的时长
+	ShowPlannedTotal bool `json:"窗口标题显示计划总时长"`
+
+	// 剩余时间达到一小时时显示为 HH:MM:SS（窗口、状态文件和 Web 页面），否则 90 分钟显示为 90:00
+	ShowHours bool `json:"显示小时"`
+
+	// 配置文件修改后自动重新读取，校验通过的时间安排在下一个大循环开始时生效
+	WatchConfig bool `json:"监视配置文件"`
+
+	// 启动时不输出横幅、配置和 Web 地址提示，便于脚本调用或嵌入其他程序；也可用 -quiet-start
+	QuietStart bool `json:"静默启动"`
+
+	Theme ThemeConfig `json:"主题"` // 窗口版配色
+
+	// 状态文件输出（供 Rainmeter、conky 等只读文件的桌面挂件使用），为空则关闭
+	StateFile string `json:"状态文件"`
+	StateDir  string `json:"状态文件目录"`
+
+	// 按目标会话时长推算时，大循环最后一个中循环在中循环总时间上的增减，由 applySessionTarget 计算
+	lastMesoAdjust time.Duration
+}
+
+var (
+	// 当前生效的配置和未叠加预设、星期覆盖的基础配置。发布后的快照不再修改，
+	// 换用配置时发布新的快照；读取方在一次处理中只取一次快照，前后读到的字段总是一致的
+	configSnap     atomic.Pointer[Config]
+	baseConfigSnap atomic.Pointer[Config]
+)
+
+func init() {
+	configSnap.Store(&Config{})
+	baseConfigSnap.Store(&Config{})
+}
+
+// currentConfig 返回当前生效配置的快照，调用方不能修改
+func currentConfig() *Config {
+	return configSnap.Load()
+}
+
+// setConfig 发布新的配置快照
+func setConfig(c Config) {
+	configSnap.Store(&c)
+}
+
+// currentBaseConfig 返回基础配置的快照，调用方不能修改
+func currentBaseConfig() *Config {
+	return baseConfigSnap.Load()
+}
+
+// setBaseConfig 发布新的基础配置；运行中要持有 pendingMu，与排队中的新配置保持一致
+func setBaseConfig(c Config) {
+	baseConfigSnap.Store(&c)
+}
+
+// Duration 是可以用 time.ParseDuration 格式的字符串配置的时长
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("时长必须是带单位的字符串（如 \"90s\"、\"25m\"）: %s", b)
+	}
+	if s == "" {
+		*d = 0
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	if d == 0 {
+		return json.Marshal("")
+	}
+	return json.Marshal(time.Duration(d).String())
+}
+
+// pickDuration 优先使用带单位的字符串字段，否则按整数字段和单位换算
+func pickDuration(d Duration, n int, unit time.Duration) time.Duration {
+	if d != 0 {
+		return time.Duration(d)
+	}
+	return time.Duration(n) * unit
+}
+
+func (c *Config) microBase() time.Duration {
+	return pickDuration(c.MicroBaseD, c.MicroBaseS, time.Second)
+}
+
+func (c *Config) microOffset() time.Duration {
+	return pickDuration(c.MicroOffsetD, c.MicroOffsetS, time.Second)
+}
+
+func (c *Config) microRest() time.Duration {
+	return pickDuration(c.MicroRestD, c.MicroRestS, time.Second)
+}
+
+func (c *Config) microRestEvery() int {
+	if c.MicroRestEvery <= 0 {
+		return 1
+	}
+	return c.MicroRestEvery
+}
+
+func (c *Config) mesoDuration() time.Duration {
+	return pickDuration(c.MesoDurationD, c.MesoDurationM, time.Minute)
+}
+
+func (c *Config) mesoRest() time.Duration {
+	return pickDuration(c.MesoRestD, c.MesoRestM, time.Minute)
+}
+
+func (c *Config) macroRest() time.Duration {
+	return pickDuration(c.MacroRestD, c.MacroRestM, time.Minute)
+}
+
+func (c *Config) macroGap() time.Duration {
+	return pickDuration(c.MacroGapD, c.MacroGapM, time.Minute)
+}
+
+// soundCooldown 返回同一事件提示音的冷却时间，0 表示不限制
+func (c *Config) soundCooldown() time.Duration {
+	if c.SoundCooldownMs == nil || *c.SoundCooldownMs <= 0 {
+		return 0
+	}
+	return time.Duration(*c.SoundCooldownMs) * time.Millisecond
+}
+
+func (c *Config) targetSession() time.Duration {
+	return pickDuration(c.TargetSessionD, c.TargetSessionM, time.Minute)
+}
+
+// loadConfig 读取配置文件，再叠加命令行参数
+func loadConfig() (Config, error) {
+	var c Config
+	if err := readConfigFile(configPath, &c); err != nil {
+		return c, err
+	}
+	applyFlagOverrides(&c)
+	return c, nil
+}
+
+func readConfigFile(path string, c *Config) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	decoder := json.NewDecoder(file)
+	return decoder.Decode(c)
+}
+
+// printEffectiveConfig 加载配置并补全默认值、叠加当天的星期覆盖后以 JSON 输出，配置无效时返回非 0 退出码
+func printEffectiveConfig() int {
+	c, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置文件失败: %v\n", err)
+		return 1
+	}
+	applyDefaults(&c)
+	// 与启动时相同的校验，无效时不输出配置
+	if err := validateConfig(c); err != nil {
+		fmt.Fprintf(os.Stderr, "配置无效: %v\n", err)
+		return 1
+	}
+	if err := validateWeekdays(c); err != nil {
+		fmt.Fprintf(os.Stderr, "配置无效: %v\n", err)
+		return 1
+	}
+	if err := validatePresets(c); err != nil {
+		fmt.Fprintf(os.Stderr, "配置无效: %v\n", err)
+		return 1
+	}
+	setConfig(c)
+	effective := scheduleForDay(c, time.Now().Weekday())
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(effective.redacted()); err != nil {
+		fmt.Fprintf(os.Stderr, "输出配置失败: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// redacted 返回隐去敏感信息的副本，任何输出配置的地方都应使用它。
+// Webhook 地址的路径和参数里常带有令牌，只保留协议和主机
+func (c Config) redacted() Config {
+	if c.WebhookURL != "" {
+		if u, err := url.Parse(c.WebhookURL); err == nil && u.Host != "" {
+			c.WebhookURL = u.Scheme + "://" + u.Host + "/***"
+		} else {
+			c.WebhookURL = "***"
+		}
+	}
+	if c.MQTTPassword != "" {
+		c.MQTTPassword = "***"
+	}
+	if c.WebLogToken != "" {
+		c.WebLogToken = "***"
+	}
+	if c.WebPassword != "" {
+		c.WebPassword = "***"
+	}
+	return c
+}
+
+const (
+	defaultPort        = 8080
+	defaultBindAddress = "0.0.0.0"
+)
+
+// applyDefaults 为未填写的字段补上默认值
+func applyDefaults(c *Config) {
+	if c.Port == 0 {
+		c.Port = defaultPort
+	}
+	if c.BindAddress == "" {
+		c.BindAddress = defaultBindAddress
+	}
+	if c.OSCPath == "" {
+		c.OSCPath = "/fanqiezhong/phase"
+	}
+	if c.MQTTTopic == "" {
+		c.MQTTTopic = "fanqiezhong/events"
+	}
+	if c.WebhookBackoffMs == 0 {
+		c.WebhookBackoffMs = 1000
+	}
+	if c.WebhookTimeoutS == 0 {
+		c.WebhookTimeoutS = 5
+	}
+	if c.WebhookDeadLetter == "" {
+		c.WebhookDeadLetter = "webhook_failed.jsonl"
+	}
+	if c.WebhookDeadLetterMaxKB == 0 {
+		c.WebhookDeadLetterMaxKB = 1024
+	}
+	if c.SoundTiming == "" {
+		c.SoundTiming = soundTimingOverlap
+	}
+	if c.MakeupMaxS == 0 {
+		c.MakeupMaxS = 600
+	}
+	if c.ActiveSkipRestMax == 0 {
+		c.ActiveSkipRestMax = 1
+	}
+	if c.AdaptiveMesoRestStepPct == 0 {
+		c.AdaptiveMesoRestStepPct = 20
+	}
+	if c.AdaptiveMesoRestMinPct == 0 {
+		c.AdaptiveMesoRestMinPct = 50
+	}
+	if c.AdaptiveMesoRestMaxPct == 0 {
+		c.AdaptiveMesoRestMaxPct = 200
+	}
+	if c.WatchdogMarginS == 0 {
+		c.WatchdogMarginS = 60
+	}
+	if c.SoundThemesDir == "" {
+		c.SoundThemesDir = "Sounds/themes"
+	}
+	if c.WebAllowOrigins == nil {
+		c.WebAllowOrigins = []string{"*"}
+	}
+	if c.Volume == nil {
+		v := volumeLevel(1)
+		c.Volume = &v
+	}
+	if c.ReadyCheckTimeoutS == 0 {
+		c.ReadyCheckTimeoutS = 120
+	}
+	if c.MicroOffsetWarnPct == 0 {
+		c.MicroOffsetWarnPct = 50
+	}
+	if c.SoundCooldownMs == nil {
+		ms := 2000
+		c.SoundCooldownMs = &ms
+	}
+	if c.Theme.Mode == themeAuto && c.Theme.LightFrom == 0 && c.Theme.LightUntil == 0 {
+		c.Theme.LightFrom, c.Theme.LightUntil = 7, 19
+	}
+	if c.SessionEndSound == "" {
+		c.SessionEndSound = "Sounds/info.mp3"
+	}
+	if c.SessionEndMessage == "" {
+		c.SessionEndMessage = "今天的专注完成了，好好休息吧！"
+	}
+}
+
+// 配置上限，防止误填极大值导致秒数换算溢出或计划表过长
+const (
+	maxPhaseDuration = 24 * time.Hour
+	maxMicrosPerMeso = 10000
+	maxCycleCount    = 1000
+)
+
+// isValidBindAddress 判断监听地址是 IP 地址或主机名（如 localhost），不能带端口
+func isValidBindAddress(addr string) bool {
+	if net.ParseIP(addr) != nil {
+		return true
+	}
+	if addr == "" || len(addr) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(addr, ".") {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return false
+		}
+		for _, r := range label {
+			if !(r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// checkDurationBounds 检查各个时长字段不为负数且不超过上限。
+// 整数字段先和上限比较再换算，避免乘以单位时溢出
+func checkDurationBounds(c Config) error {
+	fields := []struct {
+		name string
+		d    Duration
+		n    int
+		unit time.Duration
+	}{
+		{"小循环基础时间", c.MicroBaseD, c.MicroBaseS, time.Second},
+		{"小循环随机偏移", c.MicroOffsetD, c.MicroOffsetS, time.Second},
+		{"小循环休息时间", c.MicroRestD, c.MicroRestS, time.Second},
+		{"中循环总时间", c.MesoDurationD, c.MesoDurationM, time.Minute},
+		{"中循环休息时间", c.MesoRestD, c.MesoRestM, time.Minute},
+		{"大循环休息时间", c.MacroRestD, c.MacroRestM, time.Minute},
+		{"大循环间隔", c.MacroGapD, c.MacroGapM, time.Minute},
+		{"目标会话时长", c.TargetSessionD, c.TargetSessionM, time.Minute},
+		{"大循环过渡", 0, c.FinalMesoPauseS, time.Second},
+	}
+	for _, f := range fields {
+		if f.d < 0 || f.n < 0 {
+			return fmt.Errorf("%s不能为负数", f.name)
+		}
+		if time.Duration(f.d) > maxPhaseDuration || f.n > int(maxPhaseDuration/f.unit) {
+			return fmt.Errorf("%s不能超过 %v", f.name, maxPhaseDuration)
+		}
+	}
+	return nil
+}
+
+// configWarnings 返回不影响运行、但很可能是误配置的设置
+func configWarnings(c Config) []string {
+	var warnings []string
+	base, offset := c.microBase(), c.microOffset()
+	if pct := c.MicroOffsetWarnPct; pct > 0 && offset*100 > base*time.Duration(pct) {
+		warnings = append(warnings, fmt.Sprintf(
+			"小循环随机偏移 %v 超过基础时间 %v 的 %d%%，小循环会在 %v 到 %v 之间大幅波动；建议把偏移减小到 %v 以内（确实需要时可忽略此提示）",
+			offset, base, pct, base-offset, base+offset, base*time.Duration(pct)/100))
+	}
+	return warnings
+}
+
+// warnConfig 输出 configWarnings 的提示
+func warnConfig(c Config) {
+	for _, w := range configWarnings(c) {
+		progress("config_warning", 0, "配置提示: %s", w)
+	}
+}
+
+// validateConfig 检查会导致计划表无法生成的配置
+func validateConfig(c Config) error {
+	if err := checkDurationBounds(c); err != nil {
+		return err
+	}
+	if c.microBase() <= 0 {
+		return fmt.Errorf("小循环基础时间必须大于 0")
+	}
+	if c.microOffset() < 0 || c.microOffset() >= c.microBase() {
+		return fmt.Errorf("小循环随机偏移必须在 0 到小循环基础时间之间")
+	}
+	if c.mesoDuration() <= 0 {
+		return fmt.Errorf("中循环总时间必须大于 0")
+	}
+	if c.MaxSkipsPerMeso < 0 {
+		return fmt.Errorf("每个中循环最多跳过不能为负数")
+	}
+	if c.DailyFocusBudgetM < 0 || c.DailyFocusBudgetM > 24*60 {
+		return fmt.Errorf("每日专注预算分应在 0 到 %d 之间", 24*60)
+	}
+	if c.MakeupMaxS < 0 || c.MakeupMaxS > int(maxPhaseDuration/time.Second) {
+		return fmt.Errorf("补回上限秒应在 0 到 %v 之间", maxPhaseDuration)
+	}
+	if c.AdaptiveMesoRestStepPct < 0 || c.AdaptiveMesoRestMinPct < 0 {
+		return fmt.Errorf("自适应中循环休息步长百分比和下限百分比不能为负数")
+	}
+	if c.AdaptiveMesoRestMinPct > c.AdaptiveMesoRestMaxPct || c.AdaptiveMesoRestMaxPct > 1000 {
+		return fmt.Errorf("自适应中循环休息上限百分比应在下限百分比到 1000 之间")
+	}
+	if c.ActiveSkipRestS < 0 || c.ActiveSkipRestMax < 0 {
+		return fmt.Errorf("活跃时跳过小休息秒和活跃跳过小休息上限不能为负数")
+	}
+	if c.MicroRestEvery < 0 {
+		return fmt.Errorf("小循环休息间隔不能为负数")
+	}
+	// 中循环至少包含的小循环数（每个都取最长时长）；间隔不小于它时中循环内可能一次都不休息
+	if n := c.microRestEvery(); n > 1 {
+		longest := c.microBase() + c.microOffset()
+		minCount := int((c.mesoDuration() + longest - 1) / longest)
+		if n >= minCount {
+			return fmt.Errorf("小循环休息间隔 %d 过大，每个中循环可能只有 %d 个小循环", n, minCount)
+		}
+	}
+	// 计划表按整秒计算，最短的小循环不能少于 1 秒，否则无法推进
+	shortest := c.microBase() - c.microOffset()
+	if shortest < time.Second {
+		return fmt.Errorf("小循环基础时间减去随机偏移不能少于 1 秒")
+	}
+	// 计划表按最短的小循环估算长度，限制单个中循环内的小循环数量
+	if c.mesoDuration()/shortest > maxMicrosPerMeso {
+		return fmt.Errorf("中循环总时间相对小循环时长过长，每个中循环最多 %d 个小循环", maxMicrosPerMeso)
+	}
+	if c.targetSession() > 0 {
+		n, _, err := fitSessionTarget(c)
+		if err != nil {
+			return err
+		}
+		c.MesoCount = n
+	}
+	if c.MesoCount <= 0 {
+		return fmt.Errorf("中循环组数必须大于 0")
+	}
+	if c.SoundJitterMs < 0 || c.SoundJitterMs > 60000 {
+		return fmt.Errorf("提示音随机延迟毫秒应在 0-60000 之间")
+	}
+	if c.ReadyCheckTimeoutS > int(maxPhaseDuration/time.Second) {
+		return fmt.Errorf("就绪确认超时秒不能超过 %v", maxPhaseDuration)
+	}
+	if c.PauseOnBlurS < 0 || c.PauseOnBlurS > 3600 {
+		return fmt.Errorf("失去焦点暂停秒应在 0-3600 之间")
+	}
+	if c.MaxRuntimeH < 0 || c.MaxRuntimeH > 24*365 {
+		return fmt.Errorf("最长运行小时应在 0 到 %d 之间", 24*365)
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		return fmt.Errorf("端口 %d 无效，应在 1-65535 之间", c.Port)
+	}
+	if !isValidBindAddress(c.BindAddress) {
+		return fmt.Errorf("Web监听地址 %q 无效，应为 IP 地址（如 127.0.0.1）或主机名，不含端口", c.BindAddress)
+	}
+	if c.MesoCount > maxCycleCount || c.MacroCount > maxCycleCount {
+		return fmt.Errorf("中循环组数和大循环次数不能超过 %d", maxCycleCount)
+	}
+	if !isValidThemeMode(c.Theme.Mode) {
+		return fmt.Errorf("主题模式只能是 \"dark\"、\"light\" 或 \"auto\"")
+	}
+	if c.Theme.LightFrom < 0 || c.Theme.LightFrom > 23 || c.Theme.LightUntil < 0 || c.Theme.LightUntil > 24 {
+		return fmt.Errorf("主题的浅色开始小时应在 0-23，浅色结束小时应在 0-24")
+	}
+	if c.SoundTiming != soundTimingPause && c.SoundTiming != soundTimingOverlap {
+		return fmt.Errorf("提示音计时只能是 \"pause\" 或 \"overlap\"")
+	}
+	if c.SoundTheme != "" {
+		if err := checkSoundTheme(c.SoundThemesDir, c.SoundTheme); err != nil {
+			return err
+		}
+	}
+	if err := checkEventSounds(c.Sounds); err != nil {
+		return err
+	}
+	if !isValidAnnounceMode(c.AnnounceMicro) {
+		return fmt.Errorf("小循环预告只能是 \"\"、\"log\" 或 \"tts\"")
+	}
+	if !isValidAnnounceMode(c.AnnounceDailyFocus) {
+		return fmt.Errorf("中循环结束播报今日专注只能是 \"\"、\"log\" 或 \"tts\"")
+	}
+	for category, level := range c.LogLevels {
+		if !slices.Contains(logCategories, category) {
+			return fmt.Errorf("日志级别中有未知类别 %q，可选: %v", category, logCategories)
+		}
+		if !isValidLogLevel(level) {
+			return fmt.Errorf("日志级别 %s 只能是 \"info\"、\"warn\" 或 \"off\"", category)
+		}
+	}
+	if !isValidWatchdogAction(c.WatchdogAction) {
+		return fmt.Errorf("卡死处理只能是 \"\"、\"log\"、\"restart\" 或 \"exit\"")
+	}
+	if !isValidSessionIDMode(c.SessionIDMode) {
+		return fmt.Errorf("会话ID只能是 \"\"、\"process\" 或 \"macro\"")
+	}
+	if c.WatchdogMarginS < 0 {
+		return fmt.Errorf("卡死判定余量秒不能为负数")
+	}
+	if c.OSCPath != "" && !strings.HasPrefix(c.OSCPath, "/") {
+		return fmt.Errorf("OSC路径必须以 / 开头")
+	}
+	if strings.ContainsAny(c.MQTTTopic, "#+") {
+		return fmt.Errorf("MQTT主题不能包含通配符 # 或 +")
+	}
+	for _, origin := range c.WebAllowOrigins {
+		if origin != "*" && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			return fmt.Errorf("Web允许来源 %q 应为 \"*\" 或以 http:// 、https:// 开头", origin)
+		}
+	}
+	if (c.WebUsername == "") != (c.WebPassword == "") {
+		return fmt.Errorf("Web用户名和Web密码需要同时设置")
+	}
+	if c.WebLogLines < 0 || c.WebLogLines > 10000 {
+		return fmt.Errorf("Web日志条数应在 0-10000 之间")
+	}
+	if c.WebLogLines > 0 && c.WebLogToken == "" {
+		return fmt.Errorf("开启 Web日志条数 时必须设置 Web日志令牌")
+	}
+	if c.WebhookRetries < 0 || c.WebhookRetries > 10 {
+		return fmt.Errorf("Webhook重试次数应在 0-10 之间")
+	}
+	if c.WebhookBackoffMs < 0 {
+		return fmt.Errorf("Webhook重试间隔毫秒不能为负数")
+	}
+	if c.WebhookTimeoutS < 0 {
+		return fmt.Errorf("Webhook超时秒不能为负数")
+	}
+	for _, ev := range c.WebhookEvents {
+		if !isKnownEvent(ev) {
+			return fmt.Errorf("Webhook事件中有未知事件 %q，可选: %v", ev, allEvents)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+var configFileFlag = flag.String("config", "config.json", "配置文件路径，同时运行多个实例时可以各用一个配置文件")
+
+var presetFlag = flag.String("preset", "", "使用 预设 中指定名称的时间安排，覆盖 当前预设")
+
+// configOverride 是一个覆盖配置文件字段的命令行参数，只有在命令行中给出时才生效
+type configOverride struct {
+	value *int
+	apply func(c *Config, v int)
+}
+
+var configOverrides = map[string]configOverride{
+	"port":          {flag.Int("port", 0, "覆盖 端口"), func(c *Config, v int) { c.Port = v }},
+	"micro-base":    {flag.Int("micro-base", 0, "覆盖 小循环基础时间秒"), func(c *Config, v int) { c.MicroBaseS, c.MicroBaseD = v, 0 }},
+	"micro-offset":  {flag.Int("micro-offset", 0, "覆盖 小循环随机偏移秒"), func(c *Config, v int) { c.MicroOffsetS, c.MicroOffsetD = v, 0 }},
+	"micro-rest":    {flag.Int("micro-rest", 0, "覆盖 小循环休息时间秒"), func(c *Config, v int) { c.MicroRestS, c.MicroRestD = v, 0 }},
+	"meso-duration": {flag.Int("meso-duration", 0, "覆盖 中循环总时间分"), func(c *Config, v int) { c.MesoDurationM, c.MesoDurationD = v, 0 }},
+	"meso-rest":     {flag.Int("meso-rest", 0, "覆盖 中循环休息时间分"), func(c *Config, v int) { c.MesoRestM, c.MesoRestD = v, 0 }},
+	"meso-count":    {flag.Int("meso-count", 0, "覆盖 中循环组数"), func(c *Config, v int) { c.MesoCount = v }},
+	"macro-rest":    {flag.Int("macro-rest", 0, "覆盖 大循环休息时间分"), func(c *Config, v int) { c.MacroRestM, c.MacroRestD = v, 0 }},
+	"macro-count":   {flag.Int("macro-count", 0, "覆盖 大循环次数（0 表示无限循环）"), func(c *Config, v int) { c.MacroCount = v }},
+	"seed":          {flag.Int("seed", 0, "覆盖 随机种子（0 表示每次不同）"), func(c *Config, v int) { c.RandomSeed = int64(v) }},
+}
+
+// applyConfigFlag 在解析命令行参数后换用 -config 指定的配置文件
+func applyConfigFlag() {
+	configPath = *configFileFlag
+}
+
+// applyFlagOverrides 用命令行中给出的参数覆盖从配置文件读取的值，优先于配置文件
+func applyFlagOverrides(c *Config) {
+	flag.Visit(func(f *flag.Flag) {
+		if o, ok := configOverrides[f.Name]; ok {
+			o.apply(c, *o.value)
+		}
+		if f.Name == "preset" {
+			c.ActivePreset = *presetFlag
+		}
+	})
+}
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "用法: %s [参数]\n\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "-port、-micro-base 等参数覆盖配置文件中的同名设置，优先于配置文件。\n\n")
+		flag.PrintDefaults()
+	}
+}
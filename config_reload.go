@@ -0,0 +1,144 @@
+package main
+
+import (
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const configWatchInterval = 2 * time.Second
+
+var (
+	// pendingConfig 是已通过校验、等待在下一个大循环开始时生效的新配置
+	pendingMu     sync.Mutex
+	pendingConfig *Config
+)
+
+// startConfigWatcherIfNeeded 在开启 监视配置文件 时定期检查配置文件的修改时间
+func startConfigWatcherIfNeeded() {
+	if !currentConfig().WatchConfig {
+		return
+	}
+	go runConfigWatcher()
+}
+
+func runConfigWatcher() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("配置文件监视崩溃: %v", r)
+		}
+	}()
+
+	var lastMod time.Time
+	if info, err := os.Stat(configPath); err == nil {
+		lastMod = info.ModTime()
+	}
+	log.Printf("正在监视配置文件 %s", configPath)
+	soundMods := map[string]time.Time{}
+	soundFilesChanged(soundMods)
+
+	ticker := time.NewTicker(configWatchInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if timerFinished() {
+			return
+		}
+		// 提示音文件被替换后重新解码，否则会一直播放缓存中的旧声音
+		if soundFilesChanged(soundMods) {
+			progress("sounds_changed", 0, "提示音文件已修改，重新加载")
+			reloadSounds()
+		}
+		info, err := os.Stat(configPath)
+		if err != nil || info.ModTime().Equal(lastMod) {
+			continue
+		}
+		lastMod = info.ModTime()
+		checkChangedConfig()
+	}
+}
+
+// checkChangedConfig 读取并校验修改后的配置文件，通过后留待下一个大循环开始时生效，
+// 无效时继续使用原配置
+func checkChangedConfig() {
+	var c Config
+	if err := readConfigFile(configPath, &c); err != nil {
+		progress("config_rejected", 0, "配置文件已修改但无法读取，继续使用原配置: %v", err)
+		return
+	}
+	applyFlagOverrides(&c)
+	applyDefaults(&c)
+	if err := validateConfig(c); err != nil {
+		progress("config_rejected", 0, "配置文件已修改但无效，继续使用原配置: %v", err)
+		return
+	}
+	if err := validateWeekdays(c); err != nil {
+		progress("config_rejected", 0, "配置文件已修改但无效，继续使用原配置: %v", err)
+		return
+	}
+	if err := validatePresets(c); err != nil {
+		progress("config_rejected", 0, "配置文件已修改但无效，继续使用原配置: %v", err)
+		return
+	}
+
+	warnConfig(c)
+
+	queuePendingConfig(c)
+	progress("config_pending", 0, "配置文件已修改，新的时间安排将在下一个大循环开始时生效")
+}
+
+// queuePendingConfig 保存已通过校验的新配置，在下一个大循环开始时生效
+func queuePendingConfig(c Config) {
+	pendingMu.Lock()
+	pendingConfig = &c
+	pendingMu.Unlock()
+}
+
+// applyPendingConfig 在大循环开始时由计时器循环调用，换用等待生效的新配置。
+// 只替换计时相关的字段（与按星期覆盖相同，外加大循环级别的设置）、预设和 Web 服务器的地址，其他设置需要重启后生效
+func applyPendingConfig(now time.Time) {
+	// 取出新配置和发布基础配置在同一次加锁中完成，
+	// 否则 queuePreset 可能在两者之间基于旧的基础配置排队，丢掉这次的新配置
+	pendingMu.Lock()
+	c := pendingConfig
+	pendingConfig = nil
+	var prevBase *Config
+	if c != nil {
+		prevBase = currentBaseConfig()
+		setBaseConfig(*c)
+	}
+	pendingMu.Unlock()
+	if c == nil {
+		return
+	}
+
+	// 在当前配置的副本上换用新的字段，完成后整体发布，读取方不会看到换了一半的配置
+	next := *currentConfig()
+	presetChanged := c.ActivePreset != prevBase.ActivePreset
+	next.Presets, next.ActivePreset = c.Presets, c.ActivePreset
+	webMoved := c.Port != next.Port || c.BindAddress != next.BindAddress
+	next.Port, next.BindAddress = c.Port, c.BindAddress
+	copyReloadable(&next, scheduleForDay(*c, now.Weekday()))
+	appliedWeekday = now.Weekday()
+	applySessionTarget(&next)
+	setConfig(next)
+	refreshSessionPlan()
+
+	progress("config_reloaded", 0, ">>> 已换用新的配置（时间安排相关的设置已生效，其他设置需重启后生效）")
+	if presetChanged {
+		progress("preset_changed", 0, ">>> 已换用预设: %s", presetLabel(c.ActivePreset))
+	}
+	if webMoved && !isShuttingDown() {
+		// 等待进行中的请求结束，不阻塞计时
+		go restartWebServer()
+	}
+}
+
+// copyReloadable 复制可以在运行中替换的字段：按星期覆盖的计时字段和大循环级别的设置
+func copyReloadable(dst *Config, src Config) {
+	copySchedule(dst, src)
+	dst.MacroCount = src.MacroCount
+	dst.MacroGapM, dst.MacroGapD, dst.MacroGapAck = src.MacroGapM, src.MacroGapD, src.MacroGapAck
+	dst.FinalMesoPauseS = src.FinalMesoPauseS
+	dst.TargetSessionM, dst.TargetSessionD = src.TargetSessionM, src.TargetSessionD
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfigRejects(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		modify func(c *Config)
+		want   string // 错误信息中应包含的内容，为空表示应通过校验
+	}{
+		{"合法配置", func(c *Config) {}, ""},
+		{"偏移等于基础时间", func(c *Config) { c.MicroOffsetS = c.MicroBaseS }, "小循环随机偏移"},
+		{"偏移大于基础时间", func(c *Config) { c.MicroOffsetS = c.MicroBaseS + 1 }, "小循环随机偏移"},
+		{"小循环基础时间为 0", func(c *Config) { c.MicroBaseS, c.MicroOffsetS = 0, 0 }, "小循环基础时间必须大于 0"},
+		{"小循环基础时间为负数", func(c *Config) { c.MicroBaseS = -1 }, "小循环基础时间不能为负数"},
+		{"中循环总时间为 0", func(c *Config) { c.MesoDurationM = 0 }, "中循环总时间必须大于 0"},
+		{"中循环总时间为负数", func(c *Config) { c.MesoDurationM = -5 }, "中循环总时间不能为负数"},
+		{"中循环组数为 0", func(c *Config) { c.MesoCount = 0 }, "中循环组数必须大于 0"},
+		{"小循环休息为负数", func(c *Config) { c.MicroRestS = -1 }, "小循环休息时间不能为负数"},
+		{"中循环休息为负数", func(c *Config) { c.MesoRestM = -1 }, "中循环休息时间不能为负数"},
+		{"大循环休息为负数", func(c *Config) { c.MacroRestM = -1 }, "大循环休息时间不能为负数"},
+		{"端口为 0", func(c *Config) { c.Port = 0 }, "端口 0 无效"},
+		{"端口为 65536", func(c *Config) { c.Port = 65536 }, "端口 65536 无效"},
+		{"Webhook 重试间隔为负数", func(c *Config) { c.WebhookBackoffMs = -1 }, "Webhook重试间隔毫秒"},
+		{"Webhook 超时为负数", func(c *Config) { c.WebhookTimeoutS = -1 }, "Webhook超时秒"},
+		{"中循环总时间超过上限", func(c *Config) { c.MesoDurationM = 24*60 + 1 }, "中循环总时间不能超过"},
+		{"中循环内小循环过多", func(c *Config) { c.MesoDurationM, c.MicroBaseS, c.MicroOffsetS = 24*60, 8, 0 }, "每个中循环最多"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := testSchedule()
+			applyDefaults(&c)
+			tc.modify(&c)
+			err := validateConfig(c)
+			if tc.want == "" {
+				if err != nil {
+					t.Fatalf("应通过校验，得到 %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("应拒绝，错误信息包含 %q", tc.want)
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("错误信息 %q 不包含 %q", err, tc.want)
+			}
+		})
+	}
+}
+
+// TestPrintEffectiveConfigValidates 无效的配置不输出 JSON，退出码为 1；有效的配置输出后退出码为 0
+func TestPrintEffectiveConfigValidates(t *testing.T) {
+	useTestConfig(t, testSchedule())
+	prevPath := configPath
+	t.Cleanup(func() { configPath = prevPath })
+
+	for _, tc := range []struct {
+		name   string
+		modify func(c *Config)
+		want   int
+	}{
+		{"有效", func(c *Config) {}, 0},
+		{"偏移不小于基础时间", func(c *Config) { c.MicroOffsetS = c.MicroBaseS }, 1},
+		{"端口无效", func(c *Config) { c.Port = -1 }, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := testSchedule()
+			tc.modify(&c)
+			data, err := json.Marshal(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			configPath = filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(configPath, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			r, w, err := os.Pipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			stdout := os.Stdout
+			os.Stdout = w
+			code := printEffectiveConfig()
+			os.Stdout = stdout
+			w.Close()
+			out, _ := io.ReadAll(r)
+
+			if code != tc.want {
+				t.Errorf("退出码 %d，应为 %d", code, tc.want)
+			}
+			if printed := len(out) > 0; printed != (tc.want == 0) {
+				t.Errorf("退出码 %d 时输出了 %q", code, out)
+			}
+		})
+	}
+}
+
+// TestSelfTestConfigRunsStartupChecks 自检与启动时一样校验按星期和预设
+func TestSelfTestConfigRunsStartupChecks(t *testing.T) {
+	useTestConfig(t, testSchedule())
+	prevPath := configPath
+	t.Cleanup(func() { configPath = prevPath })
+
+	c := testSchedule()
+	c.Weekdays = map[string]ScheduleOverride{"周八": {}}
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkConfig(); err == nil || !strings.Contains(err.Error(), "周八") {
+		t.Errorf("checkConfig 返回 %v，应拒绝无法识别的星期", err)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// consoleLine 是 -log-json 模式下输出的一行，字段含义:
+//
+//	event        事件名，如 "micro_start"、"meso_rest_end"、"session_end"
+//	meso_index   当前中循环序号，从 1 开始；不在中循环中为 0
+//	micro_index  当前小循环序号，从 1 开始；不在小循环中为 0
+//	duration     与事件相关的时长（秒），如即将开始的阶段时长；没有时为 0
+//	timestamp    输出时间，RFC 3339 格式（含纳秒）
+//	message      同一事件在默认格式下的中文提示，去掉了缩进和 ">" 前缀
+type consoleLine struct {
+	Event      string  `json:"event"`
+	SessionID  string  `json:"session_id,omitempty"`
+	MesoIndex  int32   `json:"meso_index"`
+	MicroIndex int32   `json:"micro_index"`
+	Duration   float64 `json:"duration"`
+	Timestamp  string  `json:"timestamp"`
+	Message    string  `json:"message"`
+}
+
+var (
+	logJSON bool
+
+	// 计时器循环当前所在的中循环、小循环序号，供进度输出使用
+	progressMeso  int32
+	progressMicro int32
+
+	consoleMu sync.Mutex
+)
+
+// 按事件类别配置的日志级别
+const (
+	logLevelInfo = "info" // 输出全部消息（默认）
+	logLevelWarn = "warn" // 只输出警告和错误
+	logLevelOff  = "off"  // 不输出，错误除外
+)
+
+// logCategories 是可以在 日志级别 中配置的事件类别，事件按名称前缀归类，其余归入 other
+var logCategories = []string{"micro", "meso", "macro", "quick_focus", "cooldown", "session", "ready", "other"}
+
+func isValidLogLevel(level string) bool {
+	switch level {
+	case logLevelInfo, logLevelWarn, logLevelOff:
+		return true
+	}
+	return false
+}
+
+// eventCategory 按名称前缀返回事件的类别，如 micro_rest_start 属于 micro
+func eventCategory(event string) string {
+	for _, c := range logCategories {
+		if event == c || strings.HasPrefix(event, c+"_") {
+			return c
+		}
+	}
+	return "other"
+}
+
+// eventLogged 按事件类别的日志级别判断是否输出该事件；错误总是输出
+func eventLogged(event string) bool {
+	if strings.Contains(event, "error") {
+		return true
+	}
+	switch currentConfig().LogLevels[eventCategory(event)] {
+	case logLevelOff:
+		return false
+	case logLevelWarn:
+		return strings.Contains(event, "warning") || strings.HasSuffix(event, "_stalled") || strings.HasSuffix(event, "_rejected")
+	}
+	return true
+}
+
+func setProgressIndex(meso, micro int) {
+	atomic.StoreInt32(&progressMeso, int32(meso))
+	atomic.StoreInt32(&progressMicro, int32(micro))
+}
+
+// progress 输出一条进度消息：默认按 format 原样输出，-log-json 模式下输出一行 JSON
+func progress(event string, d time.Duration, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	recordLog("progress", msg)
+	if !eventLogged(event) {
+		return
+	}
+	if !logJSON {
+		fmt.Println(msg)
+		return
+	}
+
+	line := consoleLine{
+		Event:      event,
+		SessionID:  currentSessionID(),
+		MesoIndex:  atomic.LoadInt32(&progressMeso),
+		MicroIndex: atomic.LoadInt32(&progressMicro),
+		Duration:   d.Seconds(),
+		Timestamp:  time.Now().Format(time.RFC3339Nano),
+		Message:    strings.TrimLeft(msg, " >"),
+	}
+	consoleMu.Lock()
+	defer consoleMu.Unlock()
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.Encode(line)
+}
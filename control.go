@@ -0,0 +1,427 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// skipCh 最多缓存一个跳过请求，由 wait 消费
+	skipCh = make(chan struct{}, 1)
+
+	// 本次大循环内连续完成（未跳过）的小循环数
+	microStreak int64
+
+	// ackCh 传递用户确认和回复通道，ackPending 表示当前阶段正在等待确认（0=false, 1=true）；
+	// ackReply 是已确认、还在等待计时器进入下一阶段的回复通道，由 setCurrentTaskAt 回复
+	ackCh      = make(chan chan string, 1)
+	ackPending int32
+	ackReplyMu sync.Mutex
+	ackReply   chan string
+
+	// quickFocusCh 传递快速专注的时长，由 wait 消费；quickFocusActive 防止重叠请求
+	quickFocusCh     = make(chan time.Duration, 1)
+	quickFocusActive int32
+
+	// mesoSkips 是本中循环内已跳过的次数，受 每个中循环最多跳过 限制
+	mesoSkips int32
+
+	// endMesoPending 标记已请求提前结束当前中循环；mesoEndedEarly 统计提前结束的次数
+	endMesoPending int32
+	mesoEndedEarly int64
+
+	// 补回小循环的次数和累计时长
+	makeupCount int64
+	makeupNano  int64
+
+	// 因仍在输入而跳过的小休息次数
+	activeRestSkips int64
+
+	// 快速专注单独统计
+	quickFocusCount int64
+	quickFocusNano  int64
+
+	// pauseCh、resumeCh 传递暂停和继续请求，由 wait 消费；
+	// paused 和 pausedAtNano 属于计时状态，通过 updateTiming 修改
+	pauseCh      = make(chan struct{}, 1)
+	resumeCh     = make(chan struct{}, 1)
+	paused       int32
+	pausedAtNano int64
+
+	errNothingToSkip  = errors.New("当前没有正在计时的阶段")
+	errQuickFocusBusy = errors.New("已有快速专注正在进行或等待开始")
+	errNoAckPending   = errors.New("当前没有等待确认的阶段")
+	errNotInMesoFocus = errors.New("只能在中循环的小循环专注中结束中循环")
+	errSkipLimit      = errors.New("本中循环的跳过次数已用完")
+	errCannotPause    = errors.New("等待确认或快速专注时不能暂停")
+	errAckQuickFocus  = errors.New("等待确认时不能开始快速专注")
+	errAlreadyPaused  = errors.New("计时已经暂停")
+	errNotPaused      = errors.New("计时没有暂停")
+	// errLoopCancelled 表示计时器循环的 ctx 已被取消（看门狗重启循环或退出），正在运行的循环应直接返回
+	errLoopCancelled = errors.New("计时器循环已取消")
+)
+
+// requestSkip 请求立即结束当前阶段
+func requestSkip() error {
+	phase := getPhase()
+	if phase == phaseIdle || phase == phaseDone {
+		return errNothingToSkip
+	}
+	if limit := int32(currentConfig().MaxSkipsPerMeso); limit > 0 && atomic.LoadInt32(&mesoSkips) >= limit {
+		log.Printf("拒绝跳过请求: 本中循环已跳过 %d 次", limit)
+		return errSkipLimit
+	}
+	select {
+	case skipCh <- struct{}{}:
+		atomic.AddInt32(&mesoSkips, 1)
+		log.Printf("收到跳过请求: %s", phase)
+	default:
+		// 已有未处理的跳过请求
+	}
+	return nil
+}
+
+// skipsRemaining 返回本中循环还能跳过的次数，没有限制时返回 -1
+func skipsRemaining() int {
+	limit := currentConfig().MaxSkipsPerMeso
+	if limit <= 0 {
+		return -1
+	}
+	return nonNegativeInt(limit - int(atomic.LoadInt32(&mesoSkips)))
+}
+
+// resetMesoSkips 在每个中循环开始时清零跳过次数
+func resetMesoSkips() {
+	atomic.StoreInt32(&mesoSkips, 0)
+}
+
+func nonNegativeInt(v int) int {
+	if v < 0 {
+		return 0
+	}
+	return v
+}
+
+// requestEndMeso 提前结束当前中循环：结束正在进行的小循环并跳过剩余的小循环。
+// 只在中循环的小循环专注中有效，休息中请求会被拒绝
+func requestEndMeso() error {
+	if getPhase() != phaseMicroFocus || atomic.LoadInt32(&inMeso) == 0 {
+		return errNotInMesoFocus
+	}
+	atomic.StoreInt32(&endMesoPending, 1)
+	select {
+	case skipCh <- struct{}{}:
+	default:
+	}
+	log.Println("收到提前结束中循环请求")
+	return nil
+}
+
+// endMesoRequested 返回是否有待处理的提前结束请求，不清除该请求
+func endMesoRequested() bool {
+	return atomic.LoadInt32(&endMesoPending) == 1
+}
+
+// consumeEndMeso 返回是否有待处理的提前结束请求，并清除该请求
+func consumeEndMeso() bool {
+	return atomic.SwapInt32(&endMesoPending, 0) == 1
+}
+
+// waitForAck 进入一个等待用户确认的阶段。收到确认或跳过请求时返回 true；
+// timeout 大于 0 时，超时后自动继续并返回 false；ctx 被取消时返回 errLoopCancelled
+func waitForAck(ctx context.Context, phase string, timeout time.Duration) (bool, error) {
+	if ctx.Err() != nil {
+		return false, errLoopCancelled
+	}
+	defer heartbeat()
+
+	drain(ackCh)
+	drain(skipCh)
+
+	setCurrentTask(phase, timeout)
+	atomic.StoreInt32(&ackPending, 1)
+	defer atomic.StoreInt32(&ackPending, 0)
+
+	var timeoutC <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutC = timer.C
+	}
+
+	select {
+	case reply := <-ackCh:
+		ackReplyMu.Lock()
+		ackReply = reply
+		ackReplyMu.Unlock()
+		return true, nil
+	case <-skipCh:
+		return true, nil
+	case <-timeoutC:
+		return false, nil
+	case <-ctx.Done():
+		return false, errLoopCancelled
+	}
+}
+
+// readyCheck 在开启 休息后确认就绪 时，休息结束后停在 ready 阶段等待确认（或跳过），
+// 超时后自动开始下一个小循环。等待的时间不计入中循环
+func readyCheck(ctx context.Context) error {
+	cfg := currentConfig()
+	if !cfg.ReadyCheck {
+		return nil
+	}
+	start := time.Now()
+	timeout := time.Duration(cfg.ReadyCheckTimeoutS) * time.Second
+	if timeout < 0 {
+		timeout = 0
+	}
+	progress("ready_check", timeout, "    > 休息结束，确认就绪后开始下一个小循环。")
+	acked, err := waitForAck(ctx, phaseReady, timeout)
+	if err != nil {
+		return err
+	}
+	if acked {
+		progress("ready_confirmed", time.Since(start), "    > 已确认就绪。")
+	} else {
+		progress("ready_timeout", time.Since(start), "    > 等待就绪超时，自动开始。")
+	}
+
+	if atomic.LoadInt32(&inMeso) == 1 {
+		updateTiming(func() {
+			atomic.AddInt64(&mesoStartNano, int64(time.Since(start)))
+		})
+	}
+	return nil
+}
+
+// requestAck 确认当前等待中的阶段，返回计时器随后进入的阶段。
+// 计时器循环进入下一阶段时通过回复通道告知；超过一秒没有回复时返回当时的阶段
+func requestAck() (string, error) {
+	if atomic.LoadInt32(&ackPending) == 0 {
+		return getPhase(), errNoAckPending
+	}
+	pending := getPhase()
+	reply := make(chan string, 1)
+	select {
+	case ackCh <- reply:
+		log.Printf("收到确认: %s", pending)
+	default:
+		// 已有未处理的确认
+		return getPhase(), nil
+	}
+
+	select {
+	case phase := <-reply:
+		return phase, nil
+	case <-time.After(time.Second):
+		return getPhase(), nil
+	}
+}
+
+// replyAck 把计时器进入的阶段回复给等待中的 requestAck
+func replyAck(phase string) {
+	ackReplyMu.Lock()
+	reply := ackReply
+	ackReply = nil
+	ackReplyMu.Unlock()
+	if reply != nil {
+		reply <- phase
+	}
+}
+
+func isAwaitingAck() bool {
+	return atomic.LoadInt32(&ackPending) == 1
+}
+
+// drain 丢弃通道中遗留的请求
+func drain[T any](ch chan T) {
+	select {
+	case <-ch:
+	default:
+	}
+}
+
+// requestQuickFocus 请求打断当前计划，插入一段指定时长的专注
+func requestQuickFocus(d time.Duration) error {
+	phase := getPhase()
+	if phase == phaseIdle || phase == phaseDone {
+		return errNothingToSkip
+	}
+	if isPaused() {
+		return errAlreadyPaused
+	}
+	// waitForAck 不处理快速专注，请求会一直留到确认之后
+	if isAwaitingAck() {
+		return errAckQuickFocus
+	}
+	if !atomic.CompareAndSwapInt32(&quickFocusActive, 0, 1) {
+		return errQuickFocusBusy
+	}
+	quickFocusCh <- d
+	log.Printf("收到快速专注请求: %v", d)
+	return nil
+}
+
+// requestPause 请求暂停当前阶段，继续后从暂停的位置接着计时
+func requestPause() error {
+	phase := getPhase()
+	if phase == phaseIdle || phase == phaseDone {
+		return errNothingToSkip
+	}
+	if isAwaitingAck() || atomic.LoadInt32(&quickFocusActive) == 1 {
+		return errCannotPause
+	}
+	if isPaused() {
+		return errAlreadyPaused
+	}
+	select {
+	case pauseCh <- struct{}{}:
+		log.Printf("收到暂停请求: %s", phase)
+	default:
+		// 已有未处理的暂停请求
+	}
+	return nil
+}
+
+// requestResume 请求继续已暂停的阶段
+func requestResume() error {
+	if !isPaused() {
+		return errNotPaused
+	}
+	select {
+	case resumeCh <- struct{}{}:
+		log.Println("收到继续请求")
+	default:
+	}
+	return nil
+}
+
+func isPaused() bool {
+	return atomic.LoadInt32(&paused) == 1
+}
+
+// pauseWait 在 wait 中从 start 起暂停计时，直到收到继续或跳过请求，返回是否被跳过和继续的时刻，
+// ctx 被取消时返回 errLoopCancelled。
+// 暂停期间进度冻结，暂停的时间不计入当前阶段和中循环。当前阶段和中循环使用同一对
+// 暂停/继续时刻，多次暂停后两者的进度也不会相互偏移
+func pauseWait(ctx context.Context, start time.Time) (bool, time.Time, error) {
+	drain(resumeCh)
+	updateTiming(func() {
+		atomic.StoreInt64(&pausedAtNano, start.UnixNano())
+		atomic.StoreInt32(&paused, 1)
+	})
+	resumed := start
+	// 计时器循环被看门狗替换时也要解除暂停，否则新的循环会一直显示暂停
+	defer func() {
+		updateTiming(func() {
+			atomic.AddInt64(&mesoStartNano, int64(resumed.Sub(start)))
+			atomic.StoreInt32(&paused, 0)
+		})
+	}()
+	progress("paused", 0, "    > 已暂停。")
+
+	skipped := false
+	select {
+	case <-resumeCh:
+	case <-skipCh:
+		skipped = true
+	case <-ctx.Done():
+		return false, start, errLoopCancelled
+	}
+
+	resumed = time.Now()
+	spent := resumed.Sub(start)
+	progress("resumed", spent, "    > 继续计时（暂停了 %v）。", spent.Round(time.Second))
+	return skipped, resumed, nil
+}
+
+// runQuickFocus 在 wait 中执行一段快速专注，期间隐藏中循环进度条，
+// 结束后把中循环的起点顺延，使其进度不受打断影响；ctx 被取消时返回 errLoopCancelled
+func runQuickFocus(ctx context.Context, d time.Duration) error {
+	defer atomic.StoreInt32(&quickFocusActive, 0)
+
+	mesoWasShown := atomic.LoadInt32(&inMeso) == 1
+	updateTiming(func() { atomic.StoreInt32(&inMeso, 0) })
+
+	progress(eventQuickFocusStart, d, "    > 快速专注开始 (%v)", d)
+	playEvent(eventQuickFocusStart)
+
+	start := time.Now()
+	setCurrentTask(phaseQuickFocus, d)
+	timer := time.NewTimer(d)
+	skipped := false
+	select {
+	case <-timer.C:
+	case <-skipCh:
+		timer.Stop()
+		skipped = true
+		progress("quick_focus_skipped", 0, "    > 已跳过快速专注。")
+	case <-ctx.Done():
+		timer.Stop()
+		return errLoopCancelled
+	}
+	spent := time.Since(start)
+
+	atomic.AddInt64(&quickFocusCount, 1)
+	atomic.AddInt64(&quickFocusNano, int64(spent))
+	countDailyFocus(phaseQuickFocus, spent)
+	recordEvent(SessionEvent{
+		Timestamp:      time.Now(),
+		Phase:          phaseQuickFocus,
+		PlannedSeconds: d.Seconds(),
+		ActualSeconds:  spent.Seconds(),
+		Skipped:        skipped,
+	})
+	progress(eventQuickFocusEnd, spent, "    > 快速专注结束，用时 %v，回到原计划。", spent.Round(time.Second))
+	playEvent(eventQuickFocusEnd)
+
+	// 提示音播放的时间也不计入被打断的中循环
+	updateTiming(func() {
+		atomic.AddInt64(&mesoStartNano, int64(time.Since(start)))
+		if mesoWasShown {
+			atomic.StoreInt32(&inMeso, 1)
+		}
+	})
+	return nil
+}
+
+// shouldSkipRestForActivity 判断小休息开始时是否仍在输入，是则跳过这次休息。
+// 不会连续跳过两次，每个中循环最多跳过 活跃跳过小休息上限 次，保证中循环内仍有休息
+func shouldSkipRestForActivity(skipsThisMeso int, lastSkipped bool) bool {
+	cfg := currentConfig()
+	if cfg.ActiveSkipRestS <= 0 || lastSkipped || skipsThisMeso >= cfg.ActiveSkipRestMax {
+		return false
+	}
+	age, ok := lastInputAge()
+	return ok && age < time.Duration(cfg.ActiveSkipRestS)*time.Second
+}
+
+// skipRestForActivity 跳过小休息，并把中循环总时长减去这段休息，让进度条与实际一致
+func skipRestForActivity(rest time.Duration) {
+	atomic.AddInt64(&activeRestSkips, 1)
+	updateTiming(func() { atomic.AddInt64(&mesoDuration, -int64(rest)) })
+	progress("micro_rest_skipped", rest, "    > 检测到仍在输入，跳过本次小循环休息。")
+	log.Printf("仍在输入，跳过小循环休息 (%v)", rest)
+}
+
+// recordMicroResult 在小循环专注结束时更新连续专注计数
+func recordMicroResult(skipped bool) {
+	if skipped {
+		atomic.StoreInt64(&microStreak, 0)
+		return
+	}
+	atomic.AddInt64(&microStreak, 1)
+}
+
+func resetMicroStreak() {
+	atomic.StoreInt64(&microStreak, 0)
+}
+
+func getMicroStreak() int64 {
+	return atomic.LoadInt64(&microStreak)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQuickFocusRejectedWhileAwaitingAck(t *testing.T) {
+	useTestConfig(t, testSchedule())
+	setCurrentTask(phaseReady, 0)
+	atomic.StoreInt32(&ackPending, 1)
+	t.Cleanup(func() { atomic.StoreInt32(&ackPending, 0) })
+
+	if err := requestQuickFocus(25 * time.Minute); !errors.Is(err, errAckQuickFocus) {
+		t.Fatalf("requestQuickFocus 返回 %v，应为 errAckQuickFocus", err)
+	}
+	if atomic.LoadInt32(&quickFocusActive) != 0 {
+		t.Error("被拒绝的请求不应占用快速专注")
+	}
+	if len(quickFocusCh) != 0 {
+		t.Error("被拒绝的请求不应留在通道中")
+	}
+}
+
+func TestRequestAckReportsNextPhase(t *testing.T) {
+	useTestConfig(t, testSchedule())
+
+	// 模拟计时器循环：确认后稍等片刻再进入下一阶段，requestAck 应等到这一刻
+	go func() {
+		if _, err := waitForAck(context.Background(), phaseReady, 0); err != nil {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+		setCurrentTask(phaseMicroFocus, time.Minute)
+	}()
+	deadline := time.Now().Add(time.Second)
+	for !isAwaitingAck() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	phase, err := requestAck()
+	if err != nil {
+		t.Fatalf("requestAck 失败: %v", err)
+	}
+	if phase != phaseMicroFocus {
+		t.Errorf("requestAck 返回阶段 %q，应为 %q", phase, phaseMicroFocus)
+	}
+}
+
+// TestPauseResumePreservesRemaining 反复暂停和继续 100 次，暂停期间剩余时间冻结，
+// 累计减少的时间不超过计时器真正运行的时间（误差 1 毫秒以内）
+func TestPauseResumePreservesRemaining(t *testing.T) {
+	useTestConfig(t, testSchedule())
+	setCurrentTask(phaseIdle, 0)
+
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("等待%s超时", what)
+			}
+			time.Sleep(50 * time.Microsecond)
+		}
+	}
+	remaining := func() time.Duration {
+		return time.Duration(readStatus().CurrentRemaining() * float64(time.Second))
+	}
+
+	const duration = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	// running 是计时器最多运行了多久：从发出继续请求（或开始计时）到观察到暂停为止
+	runStart := time.Now()
+	go func() {
+		_, err := wait(ctx, phaseMicroFocus, duration)
+		done <- err
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		setCurrentTask(phaseIdle, 0)
+	})
+	waitFor("开始计时", func() bool { return getPhase() == phaseMicroFocus })
+
+	var running time.Duration
+	for i := 0; i < 100; i++ {
+		if err := requestPause(); err != nil {
+			t.Fatalf("第 %d 次暂停失败: %v", i+1, err)
+		}
+		waitFor("暂停", isPaused)
+		running += time.Since(runStart)
+
+		r := remaining()
+		// 暂停的时间要明显长于允许的误差，算进剩余时间就能发现
+		time.Sleep(3 * time.Millisecond)
+		if got := remaining(); got != r {
+			t.Fatalf("第 %d 次暂停期间剩余时间从 %v 变为 %v", i+1, r, got)
+		}
+		if lost := duration - r; lost < 0 || lost > running+time.Millisecond {
+			t.Fatalf("第 %d 次暂停时少了 %v，计时器最多只运行了 %v", i+1, lost, running)
+		}
+
+		runStart = time.Now()
+		if err := requestResume(); err != nil {
+			t.Fatalf("第 %d 次继续失败: %v", i+1, err)
+		}
+		waitFor("继续", func() bool { return !isPaused() })
+	}
+}
+
+// TestEndMesoRecordsCompletedMicro 提前结束中循环时，正在进行的小循环在历史记录和统计中算作完成而不是跳过
+func TestEndMesoRecordsCompletedMicro(t *testing.T) {
+	c := testSchedule()
+	c.HistoryFile = filepath.Join(t.TempDir(), "history.jsonl")
+	useTestConfig(t, c)
+	setCurrentTask(phaseIdle, 0)
+	setMesoTask(5 * time.Minute)
+	t.Cleanup(func() {
+		consumeEndMeso()
+		clearMesoTask()
+		setCurrentTask(phaseIdle, 0)
+	})
+
+	done := make(chan bool, 1)
+	go func() {
+		skipped, _ := wait(context.Background(), phaseMicroFocus, time.Hour)
+		done <- skipped
+	}()
+	deadline := time.Now().Add(time.Second)
+	for getPhase() != phaseMicroFocus && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if err := requestEndMeso(); err != nil {
+		t.Fatalf("提前结束中循环失败: %v", err)
+	}
+
+	select {
+	case skipped := <-done:
+		if skipped {
+			t.Error("提前结束中循环时 wait 报告阶段被跳过")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("提前结束中循环后 wait 没有返回")
+	}
+
+	stats, err := dayStats(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Skips != 0 || stats.CompletedMicros != 1 {
+		t.Errorf("统计为 %d 次跳过、%d 个完成的小循环，应为 0 次跳过、1 个完成", stats.Skips, stats.CompletedMicros)
+	}
+}
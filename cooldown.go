@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// timerLoop 是 main 和看门狗启动的计时器循环，-cooldown 时换成只运行一段冷却休息
+var timerLoop = startTimerLoop
+
+// useCooldown 检查 -cooldown 的时长，并把计时器循环换成冷却休息
+func useCooldown(d time.Duration) error {
+	if d <= 0 || d > maxPhaseDuration {
+		return fmt.Errorf("-cooldown 应在 0 到 %v 之间", maxPhaseDuration)
+	}
+	timerLoop = func(ctx context.Context) { runCooldown(ctx, d) }
+	return nil
+}
+
+// runCooldown 只运行一段休息，不进入专注循环：开始时播放 冷却提示音，
+// 结束时播放结束提示音后进入完成状态，与正常结束一样退出
+func runCooldown(ctx context.Context, d time.Duration) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("冷却休息崩溃: %v", r)
+		}
+	}()
+	log.Println("冷却休息已启动")
+	sessionStart = time.Now()
+	heartbeat()
+
+	progress("cooldown_start", d, ">>> 冷却休息 (%v)", d)
+	playEvent(eventCooldownStart)
+	if _, err := wait(ctx, phaseCooldown, d); err != nil {
+		log.Printf("旧的冷却休息已退出: %v", err)
+		return
+	}
+
+	clearMesoTask()
+	setCurrentTask(phaseDone, 0)
+	sessionElapsed = time.Since(sessionStart)
+	progress(eventCooldownEnd, sessionElapsed, ">>> 冷却休息结束。")
+	playEvent(eventCooldownEnd)
+	closeTimerDone()
+}
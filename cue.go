@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// cue 是一次事件提示，推送给需要在客户端自己播放提示音的 Web 挂件
+type cue struct {
+	Seq       int64  `json:"seq"`
+	Event     string `json:"event"`
+	Phase     string `json:"phase"`
+	PlaySound bool   `json:"play_sound"` // 客户端此时应该播放提示音
+	SoundURL  string `json:"sound_url"`  // 提示音在 Web 服务器上的地址，不在 Sounds 目录下时为空
+	Timestamp string `json:"timestamp"`
+}
+
+var (
+	cueMu   sync.Mutex
+	lastCue cue
+	cueSubs = map[chan cue]struct{}{}
+)
+
+// publishCue 记录一次事件并通知所有订阅者；订阅者来不及接收时丢弃，不阻塞计时
+func publishCue(event, sound string, playSound bool) {
+	cueMu.Lock()
+	defer cueMu.Unlock()
+
+	lastCue = cue{
+		Seq:       lastCue.Seq + 1,
+		Event:     event,
+		Phase:     getPhase(),
+		PlaySound: playSound && sound != "",
+		SoundURL:  soundURL(sound),
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+	for ch := range cueSubs {
+		select {
+		case ch <- lastCue:
+		default:
+		}
+	}
+}
+
+func subscribeCues() chan cue {
+	ch := make(chan cue, 8)
+	cueMu.Lock()
+	cueSubs[ch] = struct{}{}
+	cueMu.Unlock()
+	return ch
+}
+
+func unsubscribeCues(ch chan cue) {
+	cueMu.Lock()
+	delete(cueSubs, ch)
+	cueMu.Unlock()
+}
+
+func getLastCue() cue {
+	cueMu.Lock()
+	defer cueMu.Unlock()
+	return lastCue
+}
+
+// soundURL 把 Sounds 目录下的提示音换算成 Web 服务器上的地址
+func soundURL(path string) string {
+	path = strings.ReplaceAll(path, "\\", "/")
+	if !strings.HasPrefix(path, "Sounds/") {
+		return ""
+	}
+	return "/" + path
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// cycleTiming 记录最近一个结束的阶段的计划时长和实际时长
+type cycleTiming struct {
+	Phase   string
+	Planned time.Duration
+	Actual  time.Duration
+}
+
+var (
+	lastCycleMu sync.Mutex
+	lastCycle   cycleTiming
+)
+
+// recordCycleTiming 在阶段结束时调用；开启 显示计划与实际时长 时输出对比
+func recordCycleTiming(phase string, planned, actual time.Duration) {
+	lastCycleMu.Lock()
+	lastCycle = cycleTiming{Phase: phase, Planned: planned, Actual: actual}
+	lastCycleMu.Unlock()
+
+	if currentConfig().ShowCycleTiming {
+		msg := fmt.Sprintf("%s: 计划 %v / 实际 %v", phase, planned.Round(time.Millisecond), actual.Round(time.Millisecond))
+		progress("cycle_timing", actual, "    > %s", msg)
+		log.Println(msg)
+	}
+}
+
+func getLastCycleTiming() cycleTiming {
+	lastCycleMu.Lock()
+	defer lastCycleMu.Unlock()
+	return lastCycle
+}
+
+// String 返回 "计划 90s / 实际 91s" 形式的简短说明，没有记录时返回空字符串
+func (t cycleTiming) String() string {
+	if t.Phase == "" {
+		return ""
+	}
+	return fmt.Sprintf("计划 %.0fs / 实际 %.0fs", t.Planned.Seconds(), t.Actual.Seconds())
+}
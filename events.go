@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+// 事件名称 - 每次阶段切换对应一个事件，用于选择提示音
+const (
+	eventStartup      = "startup"
+	eventMicroEnd     = "micro_end"
+	eventMicroRestEnd = "micro_rest_end"
+	eventMesoHalf     = "meso_half"
+	eventMesoEnd      = "meso_end"
+	eventMesoRestEnd  = "meso_rest_end"
+	eventMacroEnd     = "macro_end"
+	eventMacroRestEnd = "macro_rest_end"
+	eventSessionEnd   = "session_end"
+
+	eventQuickFocusStart = "quick_focus_start"
+	eventQuickFocusEnd   = "quick_focus_end"
+
+	eventCooldownStart = "cooldown_start"
+	eventCooldownEnd   = "cooldown_end"
+)
+
+// allEvents 按发生顺序列出全部事件
+var allEvents = []string{
+	eventStartup,
+	eventMicroEnd,
+	eventMicroRestEnd,
+	eventMesoHalf,
+	eventMesoEnd,
+	eventMesoRestEnd,
+	eventMacroEnd,
+	eventMacroRestEnd,
+	eventSessionEnd,
+	eventQuickFocusStart,
+	eventQuickFocusEnd,
+	eventCooldownStart,
+	eventCooldownEnd,
+}
+
+// defaultEventSounds 各事件默认播放的提示音
+var defaultEventSounds = map[string]string{
+	eventMicroEnd:     "Sounds/warning.mp3",
+	eventMicroRestEnd: "Sounds/succeed.mp3",
+	eventMesoEnd:      "Sounds/info.mp3",
+	eventMesoRestEnd:  "Sounds/succeed.mp3",
+	eventMacroEnd:     "Sounds/info.mp3",
+	eventMacroRestEnd: "Sounds/succeed.mp3",
+
+	eventQuickFocusStart: "Sounds/succeed.mp3",
+	eventQuickFocusEnd:   "Sounds/warning.mp3",
+
+	eventCooldownEnd: "Sounds/succeed.mp3",
+}
+
+// 提示音与计时的关系
+const (
+	// soundTimingPause 提示音播完后才开始下一阶段计时，声音不占用下一阶段的时间
+	soundTimingPause = "pause"
+	// soundTimingOverlap 提示音在后台播放，下一阶段立即开始计时
+	soundTimingOverlap = "overlap"
+)
+
+var (
+	lastPlayedMu sync.Mutex
+	lastPlayed   = map[string]time.Time{}
+)
+
+func isKnownEvent(event string) bool {
+	for _, e := range allEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// eventSound 返回事件对应的提示音文件，选择了提示音主题时从主题目录中查找
+func eventSound(event string) string {
+	cfg := currentConfig()
+	// 启动、中循环过半和冷却休息的提示音只由配置决定，不使用主题
+	switch event {
+	case eventStartup:
+		return cfg.StartupSound
+	case eventMesoHalf:
+		return cfg.MesoHalfSound
+	case eventCooldownStart:
+		return cfg.CooldownSound
+	}
+	if path, ok := cfg.Sounds[event]; ok {
+		return path
+	}
+	if event == eventCooldownEnd {
+		return defaultEventSounds[eventCooldownEnd]
+	}
+	if theme := activeSoundTheme(); theme != "" {
+		path := themeSoundPath(cfg.SoundThemesDir, theme, event)
+		if event != eventSessionEnd {
+			return path
+		}
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	if event == eventSessionEnd {
+		return cfg.SessionEndSound
+	}
+	return defaultEventSounds[event]
+}
+
+// checkEventSounds 确认 事件提示音 的键都是有默认提示音的事件，且文件存在；
+// 启动、中循环过半、冷却开始和全部结束（session_end）的提示音有各自的配置项
+func checkEventSounds(sounds map[string]string) error {
+	for event, path := range sounds {
+		if _, ok := defaultEventSounds[event]; !ok {
+			return fmt.Errorf("事件提示音中的 %q 不能单独配置提示音，可选: %v", event, configurableSoundEvents())
+		}
+		if path == "" {
+			return fmt.Errorf("事件提示音 %s 的文件不能为空", event)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("事件提示音 %s 的文件不存在: %s", event, path)
+		}
+	}
+	return nil
+}
+
+// configurableSoundEvents 按发生顺序列出可以在 事件提示音 中配置的事件
+func configurableSoundEvents() []string {
+	var events []string
+	for _, ev := range allEvents {
+		if _, ok := defaultEventSounds[ev]; ok {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+// playEvent 在阶段切换时调用：通知外部集成、弹出系统通知并播放事件对应的提示音
+func playEvent(event string) {
+	cfg := currentConfig()
+	sendWebhook(event)
+	sendMQTT(event)
+	sendNotification(event)
+
+	if !claimEventSound(event, time.Now()) {
+		if eventLogged(event) {
+			log.Printf("事件 %s 仍在冷却中，跳过提示音", event)
+		}
+		return
+	}
+	sound := eventSound(event)
+	publishCue(event, sound, cfg.WebClientSound)
+	if sound == "" {
+		return
+	}
+	if cfg.SoundJitterMs > 0 {
+		// 随机延迟的提示音总在后台播放，不推迟下一阶段的开始
+		delay := time.Duration(rand.Intn(cfg.SoundJitterMs+1)) * time.Millisecond
+		go func() {
+			time.Sleep(delay)
+			playSoundSync(sound)
+		}()
+		return
+	}
+	if cfg.SoundTiming == soundTimingPause {
+		// pause：播完再返回，下一阶段在提示音结束后才开始计时
+		playSoundSync(sound)
+		return
+	}
+	// overlap：在后台播放，不占用计时器循环的时间
+	playSound(sound)
+}
+
+// claimEventSound 记录事件的播放时间；同一事件在冷却时间内只允许播放一次，
+// 避免连续快速切换阶段时提示音堆叠
+func claimEventSound(event string, now time.Time) bool {
+	cooldown := currentConfig().soundCooldown()
+
+	lastPlayedMu.Lock()
+	defer lastPlayedMu.Unlock()
+
+	if last, ok := lastPlayed[event]; ok && cooldown > 0 && now.Sub(last) < cooldown {
+		return false
+	}
+	lastPlayed[event] = now
+	return true
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+)
+
+func TestSoundCooldownZeroDisables(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		ms      *int
+		wantTwo bool // 连续两次都能播放
+	}{
+		{"不填时默认 2000", nil, false},
+		{"0 表示不限制", intPtr(0), true},
+		{"负数表示不限制", intPtr(-1), true},
+		{"500", intPtr(500), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := testSchedule()
+			c.SoundCooldownMs = tc.ms
+			useTestConfig(t, c)
+
+			now := time.Now()
+			event := "test_cooldown_" + tc.name
+			if !claimEventSound(event, now) {
+				t.Fatal("第一次应该可以播放")
+			}
+			if got := claimEventSound(event, now.Add(100*time.Millisecond)); got != tc.wantTwo {
+				t.Errorf("100ms 后再次播放 = %v，应为 %v", got, tc.wantTwo)
+			}
+		})
+	}
+}
+
+// TestPauseSoundTimingKeepsNextPhase 在 pause 模式下提示音播完才开始下一阶段，
+// 下一阶段仍计时完整的时长；overlap 模式（默认）下提示音不推迟下一阶段
+func TestPauseSoundTimingKeepsNextPhase(t *testing.T) {
+	if atomic.LoadInt32(&speakerInited) == 0 {
+		if err := initSpeaker(); err != nil {
+			t.Skipf("没有可用的音频设备: %v", err)
+		}
+	}
+	const soundLen, phaseLen = 200 * time.Millisecond, 100 * time.Millisecond
+
+	// 一段 200 毫秒的静音，直接放进缓存，不需要音频文件
+	path := "test_sound_timing.mp3"
+	buf := beep.NewBuffer(beep.Format{SampleRate: sampleRate, NumChannels: 2, Precision: 2})
+	buf.Append(beep.Silence(sampleRate.N(soundLen)))
+	soundCacheMu.Lock()
+	soundCache[path] = buf
+	soundCacheMu.Unlock()
+	t.Cleanup(func() {
+		// overlap 模式下提示音仍在后台播放，等它结束
+		for deadline := time.Now().Add(time.Second); atomic.LoadInt32(&soundsPlaying) > 0 && time.Now().Before(deadline); {
+			time.Sleep(time.Millisecond)
+		}
+		soundCacheMu.Lock()
+		delete(soundCache, path)
+		soundCacheMu.Unlock()
+		setCurrentTask(phaseIdle, 0)
+	})
+
+	for _, tc := range []struct {
+		name      string
+		timing    string // 为空时使用默认值
+		waitSound bool   // playEvent 是否等到提示音播完
+	}{
+		{"默认", "", false},
+		{soundTimingPause, soundTimingPause, true},
+		{soundTimingOverlap, soundTimingOverlap, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := testSchedule()
+			c.SoundTiming = tc.timing
+			c = *useTestConfig(t, c)
+			c.SoundCooldownMs = intPtr(0)
+			c.Sounds = map[string]string{eventMicroEnd: path}
+			setConfig(c)
+
+			soundStart := time.Now()
+			playEvent(eventMicroEnd)
+			soundEnd := time.Now()
+			// 音频设备按块消耗数据，播放时长不精确，只按是否超过一半区分
+			if played := soundEnd.Sub(soundStart); (played >= soundLen/2) != tc.waitSound {
+				t.Errorf("playEvent 用了 %v，提示音长 %v", played, soundLen)
+			}
+
+			if _, err := wait(context.Background(), phaseMicroRest, phaseLen); err != nil {
+				t.Fatal(err)
+			}
+			s := readTiming()
+			if s.cDur != int64(phaseLen) {
+				t.Errorf("下一阶段时长 %v，应为 %v", time.Duration(s.cDur), phaseLen)
+			}
+			if s.cStart < soundEnd.UnixNano() {
+				t.Error("下一阶段在 playEvent 返回之前就开始计时")
+			}
+			if took := time.Since(soundEnd); took < phaseLen {
+				t.Errorf("下一阶段只计时了 %v，应为 %v", took, phaseLen)
+			}
+		})
+	}
+}
+
+func intPtr(v int) *int { return &v }
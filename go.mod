@@ -3,6 +3,7 @@ module time_clock
 go 1.24.0
 
 require (
+	fyne.io/systray v1.12.2
 	github.com/gopxl/beep/v2 v2.1.0
 	github.com/hajimehoshi/ebiten/v2 v2.9.8
 	golang.org/x/image v0.36.0
@@ -13,6 +14,7 @@ require (
 	github.com/ebitengine/hideconsole v1.0.0 // indirect
 	github.com/ebitengine/oto/v3 v3.4.0 // indirect
 	github.com/ebitengine/purego v0.9.0 // indirect
+	github.com/godbus/dbus/v5 v5.1.0 // indirect
 	github.com/hajimehoshi/go-mp3 v0.3.4 // indirect
 	github.com/jezek/xgb v1.1.1 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
@@ -6,14 +6,15 @@ package main
 import (
 	"fmt"
 	"log"
-	"sync/atomic"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/text"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/gofont/goregular"
 	"golang.org/x/image/font/opentype"
 	"image/color"
+	"strings"
 	"time"
 )
 
@@ -28,14 +29,29 @@ type cachedValues struct {
 	mesoElapsed      float64
 	mesoRemaining    float64
 	inMeso           bool
+	phase            string
+	streak           int64
+	budget           string // 今天剩余的专注预算，没有配置预算时为空
+	paused           bool
+	awaitingAck      bool
+	title            string
 	width            int
 	height           int
 }
 
 var currentCache cachedValues
 
+const windowTitle = "番茄钟状态"
+
+// pausedMark 是暂停时显示在当前进度条中间的标记
+const pausedMark = "II"
+
+// ackMark 是等待确认（如休息后确认就绪）时显示在当前进度条中间的标记
+const ackMark = "READY?"
+
 func startGUIOrBlock() {
 	log.Println("正在启动 GUI...")
+	startTray()
 	startEbitenGUI()
 }
 
@@ -44,48 +60,51 @@ type Game struct {
 	width      int
 	height     int
 	firstFrame bool
+
+	blurSince  time.Time // 窗口失去焦点的时间，有焦点时为零值
+	autoPaused bool      // 当前的暂停是因失去焦点自动发起的
+
+	window windowState // 最近一次记录的窗口位置和大小，窗口关闭后保存
 }
 
 func (g *Game) Update() error {
-	// 每秒更新一次缓存值
-	now := time.Now().UnixNano()
+	// 计时器循环结束后关闭窗口，除非配置为保留窗口显示总结
+	if timerFinished() && (!currentConfig().KeepWindowOpen || isShuttingDown()) {
+		return ebiten.Termination
+	}
 
-	// 读取原子变量
-	cStart := atomic.LoadInt64(&currentStartNano)
-	cDur := atomic.LoadInt64(&currentDuration)
-	mStart := atomic.LoadInt64(&mesoStartNano)
-	mDur := atomic.LoadInt64(&mesoDuration)
-	inMesoFlag := atomic.LoadInt32(&inMeso) == 1
+	g.checkFocus(time.Now())
+	g.handleKeys()
 
-	// 计算当前进度
-	currentElapsed := float64(now-cStart) / 1e9
-	currentTotal := float64(cDur) / 1e9
-	if currentElapsed > currentTotal {
-		currentElapsed = currentTotal
-	}
-	currentRemaining := currentTotal - currentElapsed
-	if currentRemaining < 0 {
-		currentRemaining = 0
-	}
+	// 窗口关闭后无法再读取位置，每次更新时记下，关闭后保存
+	g.window = currentWindowState()
+
+	// 每秒更新一次缓存值
+	st := readStatus()
 
-	// 计算中循环进度
-	mesoElapsed := float64(now-mStart) / 1e9
-	mesoTotal := float64(mDur) / 1e9
-	if mesoElapsed > mesoTotal {
-		mesoElapsed = mesoTotal
+	// 更新缓存
+	// 窗口标题只在内容变化时更新
+	if title := statusTitle(st); title != currentCache.title {
+		ebiten.SetWindowTitle(title)
 	}
-	mesoRemaining := mesoTotal - mesoElapsed
-	if mesoRemaining < 0 {
-		mesoRemaining = 0
+
+	budget := ""
+	if st.HasDailyBudget {
+		budget = formatHourMinute(time.Duration(st.DailyBudgetRemaining * float64(time.Second)))
 	}
 
-	// 更新缓存
 	currentCache = cachedValues{
-		currentElapsed:   currentElapsed,
-		currentRemaining: currentRemaining,
-		mesoElapsed:      mesoElapsed,
-		mesoRemaining:    mesoRemaining,
-		inMeso:           inMesoFlag,
+		currentElapsed:   st.CurrentElapsed,
+		currentRemaining: st.CurrentRemaining(),
+		mesoElapsed:      st.MesoElapsed,
+		mesoRemaining:    st.MesoRemaining(),
+		inMeso:           st.InMeso,
+		phase:            st.Phase,
+		streak:           st.Streak,
+		budget:           budget,
+		paused:           st.Paused,
+		awaitingAck:      st.AwaitingAck,
+		title:            statusTitle(st),
 		width:            g.width,
 		height:           g.height,
 	}
@@ -93,9 +112,89 @@ func (g *Game) Update() error {
 	return nil
 }
 
+// checkFocus 在开启 失去焦点暂停秒 时，专注阶段窗口连续失去焦点超过设定秒数后自动暂停，
+// 重新获得焦点时继续。短暂切换窗口不会触发；手动暂停的阶段不会被自动继续
+func (g *Game) checkFocus(now time.Time) {
+	cfg := currentConfig()
+	if cfg.PauseOnBlurS <= 0 {
+		return
+	}
+	if ebiten.IsFocused() {
+		g.blurSince = time.Time{}
+		if g.autoPaused {
+			g.autoPaused = false
+			if requestResume() == nil {
+				progress("focus_resumed", 0, "    > 窗口重新获得焦点，继续计时。")
+			}
+		}
+		return
+	}
+
+	if g.blurSince.IsZero() {
+		g.blurSince = now
+		return
+	}
+	delay := time.Duration(cfg.PauseOnBlurS) * time.Second
+	if g.autoPaused || getPhase() != phaseMicroFocus || isPaused() || now.Sub(g.blurSince) < delay {
+		return
+	}
+	if err := requestPause(); err != nil {
+		return
+	}
+	g.autoPaused = true
+	progress("focus_paused", now.Sub(g.blurSince), "    > 窗口失去焦点 %v，自动暂停。", now.Sub(g.blurSince).Round(time.Second))
+}
+
+// handleKeys 处理窗口内的快捷键。按键按下的时刻按帧记录，每秒一次的 Update 也不会漏掉短按
+func (g *Game) handleKeys() {
+	if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
+		// 手动暂停或继续后，不再由焦点自动继续
+		g.autoPaused = false
+		var err error
+		if isPaused() {
+			err = requestResume()
+		} else {
+			err = requestPause()
+		}
+		if err != nil {
+			log.Printf("快捷键暂停/继续: %v", err)
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyS) {
+		if err := requestSkip(); err != nil {
+			log.Printf("快捷键跳过: %v", err)
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyT) {
+		floating := !ebiten.IsWindowFloating()
+		ebiten.SetWindowFloating(floating)
+		log.Printf("窗口置顶: %v", floating)
+	}
+}
+
+// statusTitle 生成窗口标题：按配置附加计划总时长和上一阶段的计划与实际时长
+func statusTitle(st Status) string {
+	cfg := currentConfig()
+	title := windowTitle
+	if cfg.ShowPlannedTotal {
+		if st.HasPlannedSession {
+			title += " - 共 " + formatHourMinute(time.Duration(st.PlannedSessionSeconds*float64(time.Second)))
+		} else {
+			// 无限循环时只显示每个大循环的时长
+			title += " - 每轮 " + formatHourMinute(time.Duration(st.PlannedMacroSeconds*float64(time.Second)))
+		}
+	}
+	if cfg.ShowCycleTiming && st.LastCycle.Phase != "" {
+		title += " - " + st.LastCycle.String()
+	}
+	return title
+}
+
 func (g *Game) Draw(screen *ebiten.Image) {
+	pal := currentPalette()
+
 	// 背景色
-	screen.Fill(color.Black)
+	screen.Fill(pal.background)
 
 	// 获取实际屏幕尺寸
 	w, h := screen.Bounds().Dx(), screen.Bounds().Dy()
@@ -103,6 +202,11 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	// 使用缓存值（无需锁）
 	cache := currentCache
 
+	if cache.phase == phaseDone {
+		drawDoneScreen(screen, w, h, pal)
+		return
+	}
+
 	// 布局逻辑
 	padding := 10
 
@@ -114,7 +218,19 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	availHeight := h - (padding * (rowCount + 1))
 	barHeight := availHeight / rowCount
 
+	// 时间文字默认按 MM:SS 留宽，显示小时时按实际文字加宽
+	timeStr := formatTime(cache.currentRemaining)
+	mesoTimeStr := formatTime(cache.mesoRemaining)
 	textWidth := 50
+	labels := []string{timeStr}
+	if cache.inMeso {
+		labels = append(labels, mesoTimeStr)
+	}
+	for _, s := range labels {
+		if tw := text.BoundString(uiFont, s).Dx(); tw > textWidth {
+			textWidth = tw
+		}
+	}
 
 	barWidth := w - (padding * 3) - textWidth
 	if barWidth < 10 {
@@ -128,12 +244,44 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		currentRatio = cache.currentElapsed / cTotal
 	}
 
+	// 暂停时进度条变暗，并在中间显示暂停标记
+	currentBar, mesoBar := pal.currentBar, pal.mesoBar
+	if isRestPhase(cache.phase) {
+		currentBar = pal.restBar
+	}
+	if cache.paused {
+		currentBar, mesoBar = dimColor(currentBar), dimColor(mesoBar)
+	}
+
 	yPos := padding
-	drawBar(screen, padding, yPos, barWidth, barHeight, currentRatio, color.RGBA{76, 175, 80, 255})
+	drawBar(screen, padding, yPos, barWidth, barHeight, currentRatio, pal.barBackground, currentBar)
 
-	timeStr := formatTime(cache.currentRemaining)
 	textY := yPos + (barHeight / 2) + 8
-	text.Draw(screen, timeStr, uiFont, padding+barWidth+padding, textY, color.White)
+	text.Draw(screen, timeStr, uiFont, padding+barWidth+padding, textY, pal.text)
+
+	// 在当前进度条左端显示阶段名和连续专注数，窗口太窄时只显示连续专注数
+	streak := ""
+	if currentConfig().ShowStreak && cache.streak > 0 {
+		streak = fmt.Sprintf("x%d", cache.streak)
+	}
+	left := strings.TrimSpace(phaseLabel(cache.phase) + " " + streak)
+	if !drawBarLabel(screen, left, padding, textY, barWidth, pal.text) && streak != "" {
+		text.Draw(screen, streak, uiFont, padding+4, textY, pal.text)
+	}
+
+	// 在当前进度条右端显示今天剩余的专注预算
+	if cache.budget != "" {
+		bounds := text.BoundString(uiFont, cache.budget)
+		text.Draw(screen, cache.budget, uiFont, padding+barWidth-bounds.Dx()-4, textY, pal.text)
+	}
+
+	if cache.paused {
+		bounds := text.BoundString(uiFont, pausedMark)
+		text.Draw(screen, pausedMark, uiFont, padding+(barWidth-bounds.Dx())/2, textY, pal.text)
+	} else if cache.awaitingAck {
+		bounds := text.BoundString(uiFont, ackMark)
+		text.Draw(screen, ackMark, uiFont, padding+(barWidth-bounds.Dx())/2, textY, pal.text)
+	}
 
 	// 如果在中循环中，绘制中循环进度
 	if cache.inMeso {
@@ -144,11 +292,61 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		}
 
 		yPos = padding + barHeight + padding
-		drawBar(screen, padding, yPos, barWidth, barHeight, mesoRatio, color.RGBA{33, 150, 243, 255}) // 蓝色
+		drawBar(screen, padding, yPos, barWidth, barHeight, mesoRatio, pal.barBackground, mesoBar)
 
-		mesoTimeStr := formatTime(cache.mesoRemaining)
 		textY = yPos + (barHeight / 2) + 8
-		text.Draw(screen, mesoTimeStr, uiFont, padding+barWidth+padding, textY, color.White)
+		text.Draw(screen, mesoTimeStr, uiFont, padding+barWidth+padding, textY, pal.text)
+		drawBarLabel(screen, mesoLabel, padding, textY, barWidth, pal.text)
+	}
+}
+
+// 进度条左端的标签。界面字体只有西文字形，与 READY?、DONE 一样使用英文
+const (
+	focusLabel = "FOCUS"
+	restLabel  = "REST"
+	mesoLabel  = "MESO"
+)
+
+// phaseLabel 返回当前进度条的标签，等待确认等没有标签的阶段返回空字符串
+func phaseLabel(phase string) string {
+	switch {
+	case phase == phaseMicroFocus || phase == phaseQuickFocus:
+		return focusLabel
+	case isRestPhase(phase):
+		return restLabel
+	}
+	return ""
+}
+
+// drawBarLabel 在进度条内左端绘制标签，返回是否绘制；窗口太窄、标签会和中间的标记或右端的文字重叠时不绘制
+func drawBarLabel(screen *ebiten.Image, label string, x, y, barWidth int, c color.Color) bool {
+	if label == "" || text.BoundString(uiFont, label).Dx()+8 > barWidth/3 {
+		return false
+	}
+	text.Draw(screen, label, uiFont, x+4, y, c)
+	return true
+}
+
+// drawDoneScreen 在全部大循环完成后显示完成画面，计时器结束后附带本次总结
+func drawDoneScreen(screen *ebiten.Image, w, h int, pal palette) {
+	lines := []string{"DONE"}
+	if timerFinished() {
+		lines = append(lines,
+			fmt.Sprintf("%d macro", currentConfig().MacroCount),
+			formatTime(sessionElapsed.Seconds()),
+		)
+	}
+
+	lineHeight := uiFont.Metrics().Height.Ceil()
+	y := (h-lineHeight*len(lines))/2 + lineHeight
+	for i, line := range lines {
+		c := pal.text
+		if i == 0 {
+			c = pal.currentBar
+		}
+		bounds := text.BoundString(uiFont, line)
+		text.Draw(screen, line, uiFont, (w-bounds.Dx())/2, y, c)
+		y += lineHeight
 	}
 }
 
@@ -161,15 +359,17 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
 var pixelImage *ebiten.Image
 
 func init() {
+	buildTags = append(buildTags, "gui")
+
 	pixelImage = ebiten.NewImage(1, 1)
 	pixelImage.Fill(color.White)
 }
 
-func drawBar(screen *ebiten.Image, x, y, width, height int, ratio float64, c color.Color) {
+func drawBar(screen *ebiten.Image, x, y, width, height int, ratio float64, bg, fg color.Color) {
 	bgOpts := &ebiten.DrawImageOptions{}
 	bgOpts.GeoM.Scale(float64(width), float64(height))
 	bgOpts.GeoM.Translate(float64(x), float64(y))
-	bgOpts.ColorM.Scale(0.2, 0.2, 0.2, 1) // 深灰色背景
+	scaleColor(bgOpts, bg)
 	screen.DrawImage(pixelImage, bgOpts)
 
 	fgWidth := float64(width) * ratio
@@ -177,24 +377,28 @@ func drawBar(screen *ebiten.Image, x, y, width, height int, ratio float64, c col
 		fgOpts := &ebiten.DrawImageOptions{}
 		fgOpts.GeoM.Scale(fgWidth, float64(height))
 		fgOpts.GeoM.Translate(float64(x), float64(y))
-		r, g, b, a := c.RGBA()
-		fgOpts.ColorM.Scale(float64(r)/65535, float64(g)/65535, float64(b)/65535, float64(a)/65535)
+		scaleColor(fgOpts, fg)
 		screen.DrawImage(pixelImage, fgOpts)
 	}
 }
 
-func formatTime(seconds float64) string {
-	sec := int(seconds)
-	m := sec / 60
-	s := sec % 60
-	return fmt.Sprintf("%02d:%02d", m, s)
+// dimColor 把颜色的不透明度减半，用于暂停时的进度条
+func dimColor(c color.RGBA) color.RGBA {
+	// color.RGBA 是预乘透明度的，各分量一起减半
+	return color.RGBA{c.R / 2, c.G / 2, c.B / 2, c.A / 2}
+}
+
+// scaleColor 把白色像素图染成指定颜色
+func scaleColor(opts *ebiten.DrawImageOptions, c color.Color) {
+	r, g, b, a := c.RGBA()
+	opts.ColorM.Scale(float64(r)/65535, float64(g)/65535, float64(b)/65535, float64(a)/65535)
 }
 
 func startEbitenGUI() {
 	tt, err := opentype.Parse(goregular.TTF)
 	if err != nil {
 		msg := fmt.Sprintf("字体错误: %v", err)
-		fmt.Println(msg)
+		progress("error", 0, "%s", msg)
 		log.Println(msg)
 		return
 	}
@@ -206,20 +410,24 @@ func startEbitenGUI() {
 	})
 	if err != nil {
 		msg := fmt.Sprintf("创建字体失败: %v", err)
-		fmt.Println(msg)
+		progress("error", 0, "%s", msg)
 		log.Println(msg)
 		return
 	}
 
-	ebiten.SetWindowSize(200, 80)
-	ebiten.SetWindowTitle("番茄钟状态")
+	restoreWindowState()
+	ebiten.SetWindowTitle(windowTitle)
 	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+	ebiten.SetWindowFloating(currentConfig().AlwaysOnTop)
 	ebiten.SetTPS(1) // 设置每秒更新1帧 - 大幅降低CPU占用
 
-	if err := ebiten.RunGame(&Game{}); err != nil {
+	log.Println("快捷键: 空格 暂停/继续，S 跳过当前阶段，T 切换窗口置顶")
+	game := &Game{}
+	if err := ebiten.RunGame(game); err != nil {
 		msg := fmt.Sprintf("GUI 错误: %v", err)
-		fmt.Println(msg)
+		progress("error", 0, "%s", msg)
 		log.Println(msg)
 	}
+	saveWindowState(game.window)
 	log.Println("GUI 已退出")
 }
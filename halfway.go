@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// startHalfwayWatcherIfNeeded 在配置了中循环过半提示音时启动监视协程
+func startHalfwayWatcherIfNeeded() {
+	if currentConfig().MesoHalfSound == "" {
+		return
+	}
+	go runHalfwayWatcher()
+}
+
+// runHalfwayWatcher 每秒检查一次中循环进度，越过一半时触发 meso_half 事件。
+// 进度按计划总时长和（快速专注后会顺延的）起点计算，暂停恢复不会重复触发；
+// 每个中循环用序号区分，只触发一次
+func runHalfwayWatcher() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("中循环过半监视崩溃: %v", r)
+		}
+	}()
+
+	var firedSeq int64
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		if timerFinished() {
+			return
+		}
+		seq := atomic.LoadInt64(&mesoSeq)
+		if seq == firedSeq {
+			continue
+		}
+		st := readStatus()
+		if !st.InMeso || st.MesoTotal <= 0 || st.MesoElapsed < st.MesoTotal/2 {
+			continue
+		}
+		firedSeq = seq
+		progress(eventMesoHalf, 0, "  >> 中循环已过半。")
+		playEvent(eventMesoHalf)
+	}
+}
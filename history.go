@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// SessionEvent 是历史记录文件中的一行：一个结束的阶段
+type SessionEvent struct {
+	Timestamp      time.Time `json:"timestamp"` // 阶段结束的时间
+	Phase          string    `json:"phase"`
+	PlannedSeconds float64   `json:"planned_seconds"`
+	ActualSeconds  float64   `json:"actual_seconds"`
+	Skipped        bool      `json:"skipped"`
+	SessionID      string    `json:"session_id,omitempty"`
+}
+
+var historyMu sync.Mutex
+
+// recordPhaseEnd 在阶段结束时调用，汇总到各项统计和历史记录
+func recordPhaseEnd(phase string, planned, actual time.Duration, skipped bool) {
+	recordCycleTiming(phase, planned, actual)
+	countDailyFocus(phase, actual)
+	recordEvent(SessionEvent{
+		Timestamp:      time.Now(),
+		Phase:          phase,
+		PlannedSeconds: planned.Seconds(),
+		ActualSeconds:  actual.Seconds(),
+		Skipped:        skipped,
+	})
+}
+
+// recordEvent 把一条记录追加到历史记录文件（JSON Lines），没有配置文件名时不记录
+func recordEvent(ev SessionEvent) {
+	path := currentConfig().HistoryFile
+	if path == "" {
+		return
+	}
+	ev.SessionID = currentSessionID()
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("写入历史记录失败: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(line); err != nil {
+		log.Printf("写入历史记录失败: %v", err)
+	}
+}
+
+// historyStats 是某一天历史记录的汇总
+type historyStats struct {
+	Date            string  `json:"date"`
+	FocusSeconds    float64 `json:"focus_seconds"`    // 小循环专注和快速专注的实际时长
+	CompletedMicros int     `json:"completed_micros"` // 没有跳过的小循环专注
+	Skips           int     `json:"skips"`            // 被跳过的阶段
+	LongestStreak   int     `json:"longest_streak"`   // 最多连续完成的小循环专注
+}
+
+// dayStats 读取历史记录文件并汇总 day 当天（本地时间）的记录；
+// 没有配置或还没有历史记录时返回全零，无法解析的行跳过
+func dayStats(day time.Time) (historyStats, error) {
+	cfg := currentConfig()
+	stats := historyStats{Date: day.Format("2006-01-02")}
+	if cfg.HistoryFile == "" {
+		return stats, nil
+	}
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	f, err := os.Open(cfg.HistoryFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return stats, nil
+		}
+		return stats, err
+	}
+	defer f.Close()
+
+	streak := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var ev SessionEvent
+		if json.Unmarshal(scanner.Bytes(), &ev) != nil {
+			continue
+		}
+		if ev.Timestamp.Local().Format("2006-01-02") != stats.Date {
+			continue
+		}
+		if ev.Phase == phaseMicroFocus || ev.Phase == phaseQuickFocus {
+			stats.FocusSeconds += ev.ActualSeconds
+		}
+		if ev.Skipped {
+			stats.Skips++
+		}
+		if ev.Phase != phaseMicroFocus {
+			continue
+		}
+		if ev.Skipped {
+			streak = 0
+			continue
+		}
+		stats.CompletedMicros++
+		streak++
+		if streak > stats.LongestStreak {
+			stats.LongestStreak = streak
+		}
+	}
+	return stats, scanner.Err()
+}
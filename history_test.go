@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryRoundTrip(t *testing.T) {
+	c := testSchedule()
+	c.HistoryFile = filepath.Join(t.TempDir(), "history.jsonl")
+	useTestConfig(t, c)
+
+	day := time.Now().Truncate(time.Second)
+	events := []SessionEvent{
+		{Timestamp: day, Phase: phaseMicroFocus, PlannedSeconds: 90, ActualSeconds: 90},
+		{Timestamp: day, Phase: phaseMicroRest, PlannedSeconds: 10, ActualSeconds: 10},
+		{Timestamp: day, Phase: phaseMicroFocus, PlannedSeconds: 80, ActualSeconds: 30, Skipped: true},
+		{Timestamp: day, Phase: phaseQuickFocus, PlannedSeconds: 300, ActualSeconds: 300},
+		{Timestamp: day, Phase: phaseMicroFocus, PlannedSeconds: 70, ActualSeconds: 70},
+		{Timestamp: day.AddDate(0, 0, -1), Phase: phaseMicroFocus, PlannedSeconds: 60, ActualSeconds: 60},
+	}
+	for _, ev := range events {
+		recordEvent(ev)
+	}
+
+	f, err := os.Open(c.HistoryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got []SessionEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var ev SessionEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			t.Fatalf("无法解析历史记录 %q: %v", scanner.Text(), err)
+		}
+		got = append(got, ev)
+	}
+	if len(got) != len(events) {
+		t.Fatalf("读回 %d 条记录，应为 %d", len(got), len(events))
+	}
+	for i, ev := range events {
+		if !got[i].Timestamp.Equal(ev.Timestamp) || got[i].Phase != ev.Phase ||
+			got[i].PlannedSeconds != ev.PlannedSeconds || got[i].ActualSeconds != ev.ActualSeconds ||
+			got[i].Skipped != ev.Skipped {
+			t.Errorf("第 %d 条读回 %+v，写入的是 %+v", i, got[i], ev)
+		}
+	}
+
+	stats, err := dayStats(day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := historyStats{
+		Date:            day.Format("2006-01-02"),
+		FocusSeconds:    90 + 30 + 300 + 70,
+		CompletedMicros: 2,
+		Skips:           1,
+		LongestStreak:   1,
+	}
+	if stats != want {
+		t.Errorf("dayStats = %+v，应为 %+v", stats, want)
+	}
+}
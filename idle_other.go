@@ -0,0 +1,11 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import "time"
+
+// lastInputAge 目前只在 Windows 上可以检测输入活动
+func lastInputAge() (time.Duration, bool) {
+	return 0, false
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var (
+	user32               = syscall.NewLazyDLL("user32.dll")
+	kernel32             = syscall.NewLazyDLL("kernel32.dll")
+	procGetLastInputInfo = user32.NewProc("GetLastInputInfo")
+	procGetTickCount     = kernel32.NewProc("GetTickCount")
+)
+
+type lastInputInfo struct {
+	cbSize uint32
+	dwTime uint32
+}
+
+// lastInputAge 返回距离最后一次键盘或鼠标输入的时间
+func lastInputAge() (time.Duration, bool) {
+	info := lastInputInfo{cbSize: uint32(unsafe.Sizeof(lastInputInfo{}))}
+	if r, _, _ := procGetLastInputInfo.Call(uintptr(unsafe.Pointer(&info))); r == 0 {
+		return 0, false
+	}
+	now, _, _ := procGetTickCount.Call()
+	// 两者都是开机后的毫秒数（uint32），相减可以正确处理约 49 天一次的回绕
+	return time.Duration(uint32(now)-info.dwTime) * time.Millisecond, true
+}
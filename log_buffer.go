@@ -0,0 +1,117 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// logLine 是一条保存下来的日志，来自 log 包或 progress 输出
+type logLine struct {
+	Seq     int64  `json:"seq"`
+	Time    string `json:"time"`
+	Source  string `json:"source"` // "log" 或 "progress"
+	Message string `json:"message"`
+}
+
+var (
+	logBufMu   sync.Mutex
+	logBuf     []logLine // 环形缓冲区，容量为 Web日志条数
+	logBufNext int
+	logBufSeq  int64
+	logSubs    = map[chan logLine]struct{}{}
+)
+
+// startLogBufferIfNeeded 在配置了 Web日志条数 时开始保存最近的日志，供 /logs 查看
+func startLogBufferIfNeeded() {
+	cfg := currentConfig()
+	if cfg.WebLogLines <= 0 {
+		return
+	}
+	logBufMu.Lock()
+	logBuf = make([]logLine, 0, cfg.WebLogLines)
+	logBufMu.Unlock()
+	log.SetOutput(io.MultiWriter(os.Stderr, logCapture{}))
+}
+
+// logCapture 把 log 包的每次输出作为一条日志保存
+type logCapture struct{}
+
+func (logCapture) Write(p []byte) (int, error) {
+	recordLog("log", strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
+
+// recordLog 去掉密钥后保存一条日志并推送给订阅者；没有开启时什么也不做
+func recordLog(source, msg string) {
+	logBufMu.Lock()
+	defer logBufMu.Unlock()
+	if cap(logBuf) == 0 {
+		return
+	}
+
+	logBufSeq++
+	line := logLine{
+		Seq:     logBufSeq,
+		Time:    time.Now().Format(time.RFC3339),
+		Source:  source,
+		Message: redactSecrets(msg),
+	}
+	if len(logBuf) < cap(logBuf) {
+		logBuf = append(logBuf, line)
+	} else {
+		logBuf[logBufNext] = line
+		logBufNext = (logBufNext + 1) % cap(logBuf)
+	}
+	for ch := range logSubs {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+// recentLogs 按时间顺序返回保存的日志
+func recentLogs() []logLine {
+	logBufMu.Lock()
+	defer logBufMu.Unlock()
+	out := make([]logLine, 0, len(logBuf))
+	out = append(out, logBuf[logBufNext:]...)
+	return append(out, logBuf[:logBufNext]...)
+}
+
+func subscribeLogs() chan logLine {
+	ch := make(chan logLine, 64)
+	logBufMu.Lock()
+	logSubs[ch] = struct{}{}
+	logBufMu.Unlock()
+	return ch
+}
+
+func unsubscribeLogs(ch chan logLine) {
+	logBufMu.Lock()
+	delete(logSubs, ch)
+	logBufMu.Unlock()
+}
+
+// redactSecrets 把配置中的密码、令牌和 Webhook 地址替换为 ***，避免通过 /logs 泄露
+func redactSecrets(msg string) string {
+	cfg := currentConfig()
+	for _, secret := range []string{cfg.MQTTPassword, cfg.WebLogToken, cfg.WebPassword} {
+		if secret != "" {
+			msg = strings.ReplaceAll(msg, secret, "***")
+		}
+	}
+	if cfg.WebhookURL != "" {
+		msg = strings.ReplaceAll(msg, cfg.WebhookURL, cfg.redacted().WebhookURL)
+		// 地址中的查询参数也可能是令牌
+		if u, err := url.Parse(cfg.WebhookURL); err == nil && u.RawQuery != "" {
+			msg = strings.ReplaceAll(msg, u.RawQuery, "***")
+		}
+	}
+	return msg
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -15,22 +17,25 @@ import (
 	"github.com/gopxl/beep/v2/speaker"
 )
 
-// Config 保存番茄钟的配置信息
-type Config struct {
-	MicroBaseS    int `json:"小循环基础时间秒"`
-	MicroOffsetS  int `json:"小循环随机偏移秒"`
-	MicroRestS    int `json:"小循环休息时间秒"`
-	MesoDurationM int `json:"中循环总时间分"`
-	MesoRestM     int `json:"中循环休息时间分"`
-	MesoCount     int `json:"中循环组数"`
-	MacroRestM    int `json:"大循环休息时间分"`
-	Port          int `json:"端口"`
-}
+// 阶段名称 - 稳定的字符串常量，外部客户端可以直接据此判断
+const (
+	phaseIdle       = "idle"
+	phaseMicroFocus = "micro_focus"
+	phaseMicroRest  = "micro_rest"
+	phaseMesoRest   = "meso_rest"
+	phaseMacroRest  = "macro_rest"
+	phaseTransition = "transition"
+	phaseQuickFocus = "quick_focus"
+	phaseMacroGap   = "macro_gap"
+	phaseReady      = "ready"
+	phaseCooldown   = "cooldown"
+	phaseDone       = "done"
+)
 
 var (
-	config        Config
 	sampleRate    beep.SampleRate = 44100
 	speakerInited int32           // 原子访问: 0=false, 1=true
+	soundsPlaying int32           // 正在播放的提示音数量
 
 	// 无锁状态变量 - 使用int64纳秒时间戳
 	currentStartNano int64 // Unix纳秒时间戳
@@ -38,6 +43,23 @@ var (
 	mesoStartNano    int64
 	mesoDuration     int64
 	inMeso           int32 // 0=false, 1=true
+	mesoSeq          int64 // 每开始一个中循环加一
+	currentPhase     atomic.Value
+
+	sessionStart   time.Time
+	sessionElapsed time.Duration         // 收尾时记录，timerDone 关闭后可以安全读取
+	timerDone      = make(chan struct{}) // 计时器循环正常结束时关闭
+
+	selfTestFlag    = flag.Bool("selftest", false, "检查配置、音频和 Web 环境后输出报告并退出")
+	versionFlag     = flag.Bool("version", false, "输出版本信息并退出")
+	printConfigFlag = flag.Bool("print-config", false, "输出补全默认值后的实际配置 (JSON) 并退出")
+	logJSONFlag     = flag.Bool("log-json", false, "以 JSON Lines 格式输出全部进度消息")
+	setupFlag       = flag.Bool("setup", false, "重新运行首次配置，写入配置文件后启动")
+	chartFlag       = flag.Bool("chart", false, "以文本甘特图输出一个大循环的计划后退出")
+	chartWidthFlag  = flag.Int("chart-width", defaultChartWidth, "-chart 图表的宽度（字符数）")
+	simulateFlag    = flag.Bool("simulate", false, "输出整个会话每个阶段的时长和时刻后退出，不计时也不播放提示音")
+	quietStartFlag  = flag.Bool("quiet-start", false, "启动时不输出横幅、配置和 Web 地址提示")
+	cooldownFlag    = flag.Duration("cooldown", 0, "只运行一段指定时长的冷却休息（如 20m）后退出，不进入专注循环")
 )
 
 func main() {
@@ -48,23 +70,97 @@ func main() {
 		}
 	}()
 
+	flag.Parse()
+	logJSON = *logJSONFlag
+	applyConfigFlag()
+
 	// 初始化随机数种子
 	rand.Seed(time.Now().UnixNano())
 
+	if *versionFlag {
+		fmt.Println(versionString())
+		return
+	}
+	if *selfTestFlag {
+		os.Exit(runSelfTest())
+	}
+	if *printConfigFlag {
+		os.Exit(printEffectiveConfig())
+	}
+
+	// 没有配置文件时先引导配置
+	if *setupFlag || configMissing() {
+		if err := runSetup(); err != nil {
+			progress("error", 0, "配置未完成: %v", err)
+			time.Sleep(5 * time.Second)
+			return
+		}
+	}
+
 	// 加载配置
-	if err := loadConfig(); err != nil {
-		msg := fmt.Sprintf("加载配置文件失败: %v", err)
-		fmt.Println(msg)
+	cfg, err := loadConfig()
+	if err != nil {
+		progress("error", 0, "加载配置文件失败: %v", err)
 		time.Sleep(5 * time.Second)
 		return
 	}
 
-	if config.Port == 0 {
-		config.Port = 8080
+	applyDefaults(&cfg)
+	if *quietStartFlag {
+		cfg.QuietStart = true
+	}
+	if err := validateConfig(cfg); err != nil {
+		progress("error", 0, "配置无效: %v", err)
+		time.Sleep(5 * time.Second)
+		return
+	}
+	if err := validateWeekdays(cfg); err != nil {
+		progress("error", 0, "配置无效: %v", err)
+		time.Sleep(5 * time.Second)
+		return
+	}
+	if err := validatePresets(cfg); err != nil {
+		progress("error", 0, "配置无效: %v", err)
+		time.Sleep(5 * time.Second)
+		return
+	}
+	setBaseConfig(cfg)
+	applySessionTarget(&cfg)
+	setConfig(cfg)
+	startLogBufferIfNeeded()
+	startSessionIDIfNeeded()
+	seedSchedule(cfg.RandomSeed)
+	warnConfig(cfg)
+	setVolume(float64(*cfg.Volume))
+	applyWeekdaySchedule(time.Now())
+	refreshSessionPlan()
+
+	if *chartFlag {
+		fmt.Print(renderScheduleChart(*chartWidthFlag))
+		return
+	}
+	if *simulateFlag {
+		simulateSession(os.Stdout, time.Now())
+		return
+	}
+	if *cooldownFlag != 0 {
+		if err := useCooldown(*cooldownFlag); err != nil {
+			progress("error", 0, "%v", err)
+			time.Sleep(5 * time.Second)
+			return
+		}
 	}
 
-	fmt.Println("番茄钟已启动")
-	fmt.Printf("配置: %+v\n", config)
+	// 按星期和预设换用时间安排后的配置
+	cfg = *currentConfig()
+	if !cfg.QuietStart {
+		progress("startup", 0, "番茄钟已启动")
+		progress("config", 0, "配置: %+v", cfg.redacted())
+	}
+	if cfg.targetSession() > 0 {
+		progress("session_target", cfg.targetSession(), "按目标会话时长 %v 安排: 每个大循环 %d 个中循环，最后一个中循环调整 %v",
+			cfg.targetSession(), cfg.MesoCount, cfg.lastMesoAdjust)
+	}
 
 	// 在后台协程中初始化音频，避免阻塞主线程
 	go func() {
@@ -74,13 +170,15 @@ func main() {
 			}
 		}()
 
-		err := speaker.Init(sampleRate, sampleRate.N(time.Second/10))
-		if err != nil {
-			msg := fmt.Sprintf("音频初始化警告: %v", err)
-			fmt.Println(msg)
+		if err := initSpeaker(); err != nil {
+			progress("audio_warning", 0, "音频初始化警告: %v", err)
+			if cfg.StartupSound != "" {
+				log.Println("音频不可用，未播放启动提示音")
+			}
 		} else {
-			atomic.StoreInt32(&speakerInited, 1)
 			log.Println("音频初始化成功")
+			preloadSounds()
+			playEvent(eventStartup)
 		}
 	}()
 
@@ -89,112 +187,358 @@ func main() {
 	// 如果包含 'web' 标签，启动 Web 服务器
 	startWebServerIfNeeded()
 
+	// 如果配置了状态文件，定期写出当前状态
+	startStateFileWriterIfNeeded()
+
+	// 如果开启了监视配置文件，修改后在下一个大循环生效
+	startConfigWatcherIfNeeded()
+
+	// Ctrl+C 或 SIGTERM 时停止计时并正常退出
+	handleSignals()
+
+	// 如果配置了最长运行小时，到时自动退出
+	startRuntimeLimitIfNeeded()
+
 	// 启动核心逻辑循环
-	go startTimerLoop()
+	go timerLoop(newLoopContext())
+
+	// 如果配置了卡死处理，监视计时器循环的心跳
+	startWatchdogIfNeeded()
+
+	// 如果配置了中循环过半提示音，监视中循环进度
+	startHalfwayWatcherIfNeeded()
 
 	// 如果包含 'gui' 标签，启动 GUI，否则阻塞
 	startGUIOrBlock()
 }
 
-func startTimerLoop() {
+// startTimerLoop 运行全部大循环。ctx 被取消（看门狗重启循环或退出）后，
+// 正在等待的阶段返回 errLoopCancelled，该循环随即返回，不再修改计时状态
+func startTimerLoop(ctx context.Context) {
 	defer func() {
 		if r := recover(); r != nil {
 			log.Printf("计时器循环崩溃: %v", r)
 		}
 	}()
 	log.Println("计时器循环已启动")
-	for {
-		runMacroCycle()
+	sessionStart = time.Now()
+	heartbeat()
+	for i := 0; ; i++ {
+		// 大循环次数可能在重新读取配置后改变，每次都取最新的快照
+		cfg := currentConfig()
+		if cfg.MacroCount > 0 && i >= cfg.MacroCount {
+			break
+		}
+		isLast := cfg.MacroCount > 0 && i == cfg.MacroCount-1
+		renewSessionIDForMacro(i == 0)
+		if err := runMacroCycle(ctx, isLast); err != nil {
+			log.Printf("旧的计时器循环已退出: %v", err)
+			return
+		}
 	}
+
+	runWindDown()
+	closeTimerDone()
 }
 
-func loadConfig() error {
-	file, err := os.Open("config.json")
-	if err != nil {
-		return err
+// timerFinished 非阻塞地判断计时器循环是否已经结束
+func timerFinished() bool {
+	select {
+	case <-timerDone:
+		return true
+	default:
+		return false
 	}
-	defer file.Close()
-	decoder := json.NewDecoder(file)
-	return decoder.Decode(&config)
 }
 
-func runMacroCycle() {
-	fmt.Println(">>> 开始大循环")
-	for i := 0; i < config.MesoCount; i++ {
-		isLast := (i == config.MesoCount-1)
-		runMesoCycle(i+1, isLast)
+func runMacroCycle(ctx context.Context, isLastMacro bool) error {
+	// 配置文件修改过或跨天运行时，在大循环边界换用新的时间安排
+	applyPendingConfig(time.Now())
+	applyWeekdaySchedule(time.Now())
+	cfg := currentConfig()
+
+	setProgressIndex(0, 0)
+	progress("macro_start", 0, ">>> 开始大循环")
+	resetMicroStreak()
+	for i := 0; i < cfg.MesoCount; i++ {
+		isLast := (i == cfg.MesoCount-1)
+		if err := runMesoCycle(ctx, i+1, isLast); err != nil {
+			return err
+		}
 	}
 
-	fmt.Println(">>> 大循环结束。")
-	playSound("Sounds/info.mp3")
+	setProgressIndex(0, 0)
+	progress(eventMacroEnd, 0, ">>> 大循环结束。")
+	playEvent(eventMacroEnd)
 
-	fmt.Printf(">>> 大循环休息 (%d 分)\n", config.MacroRestM)
+	// 最后一个大循环不再休息，交给收尾流程
+	if isLastMacro {
+		return nil
+	}
+
+	progress("macro_rest_start", cfg.macroRest(), ">>> 大循环休息 (%v)", cfg.macroRest())
 	clearMesoTask()
-	wait(time.Duration(config.MacroRestM) * time.Minute)
+	if _, err := wait(ctx, phaseMacroRest, cfg.macroRest()); err != nil {
+		return err
+	}
+
+	progress(eventMacroRestEnd, 0, ">>> 大循环休息结束。")
+	playEvent(eventMacroRestEnd)
 
-	fmt.Println(">>> 大循环休息结束。")
-	playSound("Sounds/succeed.mp3")
+	return runMacroGap(ctx)
 }
 
-func runMesoCycle(index int, isLastMeso bool) {
-	fmt.Printf("  >> 开始中循环 %d/%d\n", index, config.MesoCount)
+// runMacroGap 在大循环休息之后、下一个大循环之前插入额外的间隔，
+// 配置为需要确认时，间隔结束后一直等到用户确认再开始下一个大循环
+func runMacroGap(ctx context.Context) error {
+	cfg := currentConfig()
+	if gap := cfg.macroGap(); gap > 0 {
+		progress("macro_gap_start", gap, ">>> 大循环间隔 (%v)", gap)
+		if _, err := wait(ctx, phaseMacroGap, gap); err != nil {
+			return err
+		}
+	}
+	if cfg.MacroGapAck {
+		progress("macro_gap_ack", 0, ">>> 等待确认后开始下一个大循环。")
+		if _, err := waitForAck(ctx, phaseMacroGap, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runMesoCycle(ctx context.Context, index int, isLastMeso bool) error {
+	cfg := currentConfig()
+	setProgressIndex(index, 0)
+	progress("meso_start", 0, "  >> 开始中循环 %d/%d", index, cfg.MesoCount)
+	consumeEndMeso() // 丢弃上一个中循环遗留的请求
+	resetMesoSkips()
 
 	// 规划时间表
 	// 目标时间转换为秒
-	targetDuration := time.Duration(config.MesoDurationM) * time.Minute
-	microDurations := planMesoSchedule(targetDuration)
+	targetDuration := mesoTargetDuration(cfg, isLastMeso)
+	microDurations, fallback := withFallbackMicro(cfg, planMesoSchedule(cfg, targetDuration, scheduleRand.Intn))
+	if fallback {
+		progress("meso_plan_error", targetDuration, "  >> 错误: 中循环 %d 没有规划出任何小循环，改为运行一个 %v 的小循环", index, cfg.microBase())
+	}
+	// 包含小休息在内的实际总时长，进度条和计划输出都使用它，而不是随机取整前的目标时长
+	plannedTotal := mesoTotalDuration(cfg, microDurations)
+	recordMesoPlan(index, targetDuration, microDurations, plannedTotal, fallback)
 
-	// 计算包含休息在内的总时长，用于UI显示
-	totalMesoDuration := time.Duration(0)
-	for i, d := range microDurations {
-		totalMesoDuration += d
-		if i < len(microDurations)-1 {
-			totalMesoDuration += time.Duration(config.MicroRestS) * time.Second
+	setMesoTask(plannedTotal)
+
+	progress("meso_plan", plannedTotal, "  >> 计划: %d 个小循环。总时长: %v（目标 %v）", len(microDurations), plannedTotal, targetDuration)
+
+	// 本中循环内因仍在输入而跳过的小休息次数，以及上一次小休息是否被跳过
+	activeSkips, lastRestSkipped := 0, false
+	// 本中循环内跳过而少专注的时间，开启补回时在中循环末尾补上
+	missed, endedEarly := time.Duration(0), false
+	// 本中循环内跳过的小循环数，用于自适应中循环休息
+	skippedMicros := 0
+	for i, duration := range microDurations {
+		setProgressIndex(index, i+1)
+		progress("micro_start", duration, "    > 小循环 %d/%d: %.0f秒", i+1, len(microDurations), duration.Seconds())
+		announce(cfg.AnnounceMicro, fmt.Sprintf("下一个小循环: %.0f 秒", duration.Seconds()))
+		skipped, err := wait(ctx, phaseMicroFocus, duration)
+		if err != nil {
+			return err
+		}
+
+		// 提前结束中循环：当前小循环算作完成，进度条走满，直接进入中循环结束
+		if consumeEndMeso() {
+			recordMicroResult(false)
+			atomic.AddInt64(&mesoEndedEarly, 1)
+			updateTiming(func() {
+				atomic.StoreInt64(&mesoStartNano, time.Now().UnixNano()-atomic.LoadInt64(&mesoDuration))
+			})
+			progress("meso_ended_early", 0, "  >> 已提前结束本中循环，跳过剩余 %d 个小循环。", len(microDurations)-i-1)
+			endedEarly = true
+			break
+		}
+		recordMicroResult(skipped)
+		if skipped {
+			skippedMicros++
+			missed += duration - getLastCycleTiming().Actual
+		}
+
+		progress(eventMicroEnd, 0, "    > 小循环结束。")
+		playEvent(eventMicroEnd)
+
+		// 按配置的间隔进行小休息，最后一个小循环之后不休息
+		if restAfterMicro(cfg, i, len(microDurations)) {
+			if shouldSkipRestForActivity(activeSkips, lastRestSkipped) {
+				activeSkips++
+				lastRestSkipped = true
+				skipRestForActivity(cfg.microRest())
+				continue
+			}
+			lastRestSkipped = false
+
+			progress("micro_rest_start", cfg.microRest(), "    > 小循环休息 (%v)", cfg.microRest())
+			if _, err := wait(ctx, phaseMicroRest, cfg.microRest()); err != nil {
+				return err
+			}
+			progress(eventMicroRestEnd, 0, "    > 小循环休息结束。")
+			playEvent(eventMicroRestEnd)
+			if err := readyCheck(ctx); err != nil {
+				return err
+			}
 		}
 	}
-	setMesoTask(totalMesoDuration)
 
-	fmt.Printf("  >> 计划: %d 个小循环。总时长: %v\n", len(microDurations), targetDuration)
+	if cfg.MakeupSkipped && !endedEarly {
+		if err := runMakeupRound(ctx, missed); err != nil {
+			return err
+		}
+	}
 
-	for i, duration := range microDurations {
-		fmt.Printf("    > 小循环 %d/%d: %.0f秒\n", i+1, len(microDurations), duration.Seconds())
-		wait(duration)
-
-		fmt.Println("    > 小循环结束。")
-		playSound("Sounds/warning.mp3")
-
-		// 如果不是最后一个小循环，进行小休息
-		if i < len(microDurations)-1 {
-			fmt.Printf("    > 小循环休息 (%d 秒)\n", config.MicroRestS)
-			wait(time.Duration(config.MicroRestS) * time.Second)
-			fmt.Println("    > 小循环休息结束。")
-			playSound("Sounds/succeed.mp3")
+	setProgressIndex(index, 0)
+	if !isLastMeso {
+		clearMesoTask()
+
+		progress(eventMesoEnd, 0, "  >> 中循环结束。")
+		playEvent(eventMesoEnd)
+		announceDailyFocus()
+
+		rest := cfg.mesoRest()
+		if cfg.AdaptiveMesoRest {
+			var reason string
+			rest, reason = adaptiveMesoRest(cfg, rest, skippedMicros)
+			progress("meso_rest_adapted", rest, "  >> 自适应中循环休息: %s", reason)
+			log.Printf("自适应中循环休息: %s", reason)
+		}
+		progress("meso_rest_start", rest, "  >> 中循环休息 (%v)", rest)
+		if _, err := wait(ctx, phaseMesoRest, rest); err != nil {
+			return err
 		}
+
+		progress(eventMesoRestEnd, 0, "  >> 中循环休息结束。")
+		playEvent(eventMesoRestEnd)
+		return readyCheck(ctx)
 	}
 
+	progress(eventMesoEnd, 0, "  >> 本组最后一个中循环结束。进入大循环休息序列。")
+	announceDailyFocus()
+
+	// 保留已走满的中循环进度条，短暂过渡后再切换到大循环休息
+	if pause := time.Duration(cfg.FinalMesoPauseS) * time.Second; pause > 0 {
+		if _, err := wait(ctx, phaseTransition, pause); err != nil {
+			return err
+		}
+	}
 	clearMesoTask()
+	return nil
+}
 
-	if !isLastMeso {
-		fmt.Println("  >> 中循环结束。")
-		playSound("Sounds/info.mp3")
+// runMakeupRound 把本中循环跳过的专注时间合并成一个补回小循环，长度不超过 补回上限秒
+func runMakeupRound(ctx context.Context, missed time.Duration) error {
+	if limit := time.Duration(currentConfig().MakeupMaxS) * time.Second; missed > limit {
+		missed = limit
+	}
+	missed = missed.Round(time.Second)
+	if missed < time.Second {
+		return nil
+	}
 
-		fmt.Printf("  >> 中循环休息 (%d 分)\n", config.MesoRestM)
-		wait(time.Duration(config.MesoRestM) * time.Minute)
+	// 中循环进度条加上补回的时长
+	updateTiming(func() { atomic.AddInt64(&mesoDuration, int64(missed)) })
 
-		fmt.Println("  >> 中循环休息结束。")
-		playSound("Sounds/succeed.mp3")
-	} else {
-		fmt.Println("  >> 本组最后一个中循环结束。进入大循环休息序列。")
+	progress("makeup_start", missed, "    > 补回跳过的专注: %.0f秒", missed.Seconds())
+	start := time.Now()
+	if _, err := wait(ctx, phaseMicroFocus, missed); err != nil {
+		return err
+	}
+	atomic.AddInt64(&makeupCount, 1)
+	atomic.AddInt64(&makeupNano, int64(time.Since(start)))
+
+	progress(eventMicroEnd, 0, "    > 补回小循环结束。")
+	playEvent(eventMicroEnd)
+	return nil
+}
+
+// withFallbackMicro 在规划为空时改为一个基础时长的小循环，返回是否使用了替代。
+// 正常配置下至少规划一个小循环；万一为空，中循环会瞬间“完成”
+func withFallbackMicro(c *Config, micros []time.Duration) ([]time.Duration, bool) {
+	if len(micros) > 0 {
+		return micros, false
+	}
+	return []time.Duration{c.microBase()}, true
+}
+
+// mesoTotalDuration 返回中循环的实际总时长：所有小循环加上它们之间的休息，
+// 最后一个小循环之后没有小休息
+func mesoTotalDuration(c *Config, microDurations []time.Duration) time.Duration {
+	total := time.Duration(0)
+	for i, d := range microDurations {
+		total += d
+		if restAfterMicro(c, i, len(microDurations)) {
+			total += c.microRest()
+		}
+	}
+	return total
+}
+
+// restAfterMicro 判断第 i 个（从 0 开始）小循环之后是否有小休息：
+// 每完成 小循环休息间隔 个小循环休息一次，最后一个小循环之后没有小休息
+func restAfterMicro(c *Config, i, count int) bool {
+	return i < count-1 && (i+1)%c.microRestEvery() == 0
+}
+
+// adaptiveMesoRest 按中循环内跳过的小循环数调整中循环休息：没有跳过时缩短一个步长，
+// 每跳过一次延长一个步长，并限制在配置的上下限之间；返回调整后的时长和原因说明
+func adaptiveMesoRest(c *Config, base time.Duration, skipped int) (time.Duration, string) {
+	step := c.AdaptiveMesoRestStepPct
+	pct := 100 - step
+	reason := "本中循环没有跳过小循环"
+	if skipped > 0 {
+		pct = 100 + step*skipped
+		reason = fmt.Sprintf("本中循环跳过了 %d 个小循环", skipped)
+	}
+	if pct < c.AdaptiveMesoRestMinPct {
+		pct = c.AdaptiveMesoRestMinPct
+	}
+	if pct > c.AdaptiveMesoRestMaxPct {
+		pct = c.AdaptiveMesoRestMaxPct
+	}
+	rest := (base * time.Duration(pct) / 100).Round(time.Second)
+	return rest, fmt.Sprintf("%s，休息 %v（%d%% × %v）", reason, rest, pct, base)
+}
+
+// runWindDown 在完成全部大循环后执行收尾：切换到完成画面、输出总结并播放结束提示音
+func runWindDown() {
+	cfg := currentConfig()
+	clearMesoTask()
+	setCurrentTask(phaseDone, 0)
+
+	sessionElapsed = time.Since(sessionStart)
+
+	setProgressIndex(0, 0)
+	progress(eventSessionEnd, sessionElapsed, ">>> 全部大循环已完成。")
+	progress("session_summary", sessionElapsed, ">>> %s（共 %d 个大循环，用时 %v）",
+		cfg.SessionEndMessage, cfg.MacroCount, sessionElapsed.Round(time.Minute))
+	playEvent(eventSessionEnd)
+}
+
+// scheduleRand 生成小循环时长，只在计时器循环中使用
+var scheduleRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+// seedSchedule 在配置了随机种子时改用固定种子，使每次运行的时间安排相同
+func seedSchedule(seed int64) {
+	if seed == 0 {
+		return
 	}
+	scheduleRand = rand.New(rand.NewSource(seed))
+	log.Printf("使用固定随机种子: %d", seed)
 }
 
-// planMesoSchedule 生成一系列小循环的时长
-func planMesoSchedule(targetTotal time.Duration) []time.Duration {
+// planMesoSchedule 生成一系列小循环的时长，intn 提供随机数，
+// 计时使用 scheduleRand，图表等需要稳定结果的地方传入固定种子的随机源
+func planMesoSchedule(c *Config, targetTotal time.Duration, intn func(n int) int) []time.Duration {
 	// 转换为秒进行计算
 	targetSec := int(targetTotal.Seconds())
-	base := config.MicroBaseS
-	offset := config.MicroOffsetS
-	rest := config.MicroRestS
+	base := int(c.microBase().Seconds())
+	offset := int(c.microOffset().Seconds())
+	rest := int(c.microRest().Seconds())
 
 	minDur := base - offset
 	maxDur := base + offset
@@ -205,7 +549,7 @@ func planMesoSchedule(targetTotal time.Duration) []time.Duration {
 	// 循环生成直到总时间达到目标
 	for {
 		// 在 [minDur, maxDur] 范围内完全随机
-		d := minDur + rand.Intn(maxDur-minDur+1)
+		d := minDur + intn(maxDur-minDur+1)
 		durations = append(durations, time.Duration(d)*time.Second)
 		currentTotal += d
 
@@ -214,8 +558,10 @@ func planMesoSchedule(targetTotal time.Duration) []time.Duration {
 			break
 		}
 
-		// 加上休息时间用于下一次判断
-		currentTotal += rest
+		// 加上休息时间用于下一次判断（只在需要休息的小循环之后）
+		if len(durations)%c.microRestEvery() == 0 {
+			currentTotal += rest
+		}
 
 		// 再次检查
 		if currentTotal >= targetSec {
@@ -226,61 +572,201 @@ func planMesoSchedule(targetTotal time.Duration) []time.Duration {
 	return durations
 }
 
-func playSound(path string) {
+// speakerInitMu 避免同时播放的提示音重复初始化音频设备
+var speakerInitMu sync.Mutex
+
+// initSpeaker 初始化音频输出设备
+func initSpeaker() error {
+	speakerInitMu.Lock()
+	defer speakerInitMu.Unlock()
+	if err := speaker.Init(sampleRate, sampleRate.N(time.Second/10)); err != nil {
+		return err
+	}
+	atomic.StoreInt32(&speakerInited, 1)
+	return nil
+}
+
+// decodeSound 打开并解码 mp3 文件，调用方负责关闭返回的 streamer
+func decodeSound(path string) (beep.StreamSeekCloser, beep.Format, error) {
 	// 在 Windows 上，使用 filepath.FromSlash 确保分隔符正确
 	path = filepath.FromSlash(path)
 
 	f, err := os.Open(path)
 	if err != nil {
-		fmt.Printf("打开音频文件失败 %s: %v\n", path, err)
-		return
+		return nil, beep.Format{}, fmt.Errorf("打开音频文件失败 %s: %w", path, err)
 	}
-	defer f.Close()
 
+	// 解码成功后 streamer.Close 会一并关闭文件
 	streamer, format, err := mp3.Decode(f)
 	if err != nil {
-		fmt.Printf("解码 mp3 失败 %s: %v\n", path, err)
+		f.Close()
+		return nil, beep.Format{}, fmt.Errorf("解码 mp3 失败 %s: %w", path, err)
+	}
+	return streamer, format, nil
+}
+
+// playSound 在后台播放提示音并立即返回，不占用调用方（如计时器循环）的时间
+func playSound(path string) {
+	go playSoundSync(path)
+}
+
+// playSoundSync 播放提示音，播完才返回；多个提示音可以同时播放，由 speaker 混音
+func playSoundSync(path string) {
+	if isShuttingDown() {
 		return
 	}
-	defer streamer.Close()
+	var s beep.Streamer
+	if buf := cachedSound(path); buf != nil {
+		// 预加载过的提示音直接从内存播放
+		s = buf.Streamer(0, buf.Len())
+	} else {
+		streamer, format, err := decodeSound(path)
+		if err != nil {
+			progress("sound_error", 0, "%v", err)
+			return
+		}
+		defer streamer.Close()
 
-	// 如有必要进行重采样
-	var s beep.Streamer = streamer
-	if format.SampleRate != sampleRate {
-		s = beep.Resample(4, format.SampleRate, sampleRate, streamer)
+		// 如有必要进行重采样
+		s = streamer
+		if format.SampleRate != sampleRate {
+			s = beep.Resample(4, format.SampleRate, sampleRate, streamer)
+		}
 	}
+	s = withVolume(s)
 
 	if atomic.LoadInt32(&speakerInited) == 0 {
 		// 尝试初始化（应该已经在 main 中完成，但以防万一）
-		speaker.Init(sampleRate, sampleRate.N(time.Second/10))
-		atomic.StoreInt32(&speakerInited, 1)
+		speakerInitMu.Lock()
+		if atomic.LoadInt32(&speakerInited) == 0 {
+			speaker.Init(sampleRate, sampleRate.N(time.Second/10))
+			atomic.StoreInt32(&speakerInited, 1)
+		}
+		speakerInitMu.Unlock()
 	}
 
-	done := make(chan bool)
+	atomic.AddInt32(&soundsPlaying, 1)
+	defer atomic.AddInt32(&soundsPlaying, -1)
+
+	// 回调在 speaker 的混音协程中执行，只关闭通道，不会阻塞混音
+	done := make(chan struct{})
 	speaker.Play(beep.Seq(s, beep.Callback(func() {
-		done <- true
+		close(done)
 	})))
 
 	<-done
 }
 
-// 状态管理辅助函数 - 无锁实现
-func setCurrentTask(duration time.Duration) {
-	atomic.StoreInt64(&currentStartNano, time.Now().UnixNano())
-	atomic.StoreInt64(&currentDuration, int64(duration))
+// 状态管理辅助函数 - 读取无锁，写入通过 updateTiming 保持一致
+func setCurrentTask(phase string, duration time.Duration) {
+	setCurrentTaskAt(phase, duration, time.Now())
+}
+
+func setCurrentTaskAt(phase string, duration time.Duration, start time.Time) {
+	heartbeat()
+	updateTiming(func() {
+		currentPhase.Store(phase)
+		atomic.StoreInt64(&currentStartNano, start.UnixNano())
+		atomic.StoreInt64(&currentDuration, int64(duration))
+	})
+	replyAck(phase)
+
+	sendOSC(phase, duration-time.Since(start))
+}
+
+func getPhase() string {
+	if p, ok := currentPhase.Load().(string); ok {
+		return p
+	}
+	return phaseIdle
 }
 
 func setMesoTask(duration time.Duration) {
-	atomic.StoreInt64(&mesoStartNano, time.Now().UnixNano())
-	atomic.StoreInt64(&mesoDuration, int64(duration))
-	atomic.StoreInt32(&inMeso, 1)
+	atomic.AddInt64(&mesoSeq, 1)
+	updateTiming(func() {
+		atomic.StoreInt64(&mesoStartNano, time.Now().UnixNano())
+		atomic.StoreInt64(&mesoDuration, int64(duration))
+		atomic.StoreInt32(&inMeso, 1)
+	})
 }
 
 func clearMesoTask() {
-	atomic.StoreInt32(&inMeso, 0)
+	updateTiming(func() { atomic.StoreInt32(&inMeso, 0) })
 }
 
-func wait(duration time.Duration) {
-	setCurrentTask(duration)
-	time.Sleep(duration)
+// endPhaseEarly 在收到跳过请求时结束当前阶段，返回是否算作跳过：
+// 提前结束中循环的请求同样通过 skipCh 唤醒，这时小循环按完成记录，不计入跳过
+func endPhaseEarly(phase string, duration, actual time.Duration) bool {
+	if endMesoRequested() {
+		recordPhaseEnd(phase, duration, actual, false)
+		return false
+	}
+	progress("phase_skipped", 0, "    > 已跳过当前阶段。")
+	recordPhaseEnd(phase, duration, actual, true)
+	return true
+}
+
+// wait 计时一个阶段，返回该阶段是否被跳过；ctx 被取消时返回 errLoopCancelled
+func wait(ctx context.Context, phase string, duration time.Duration) (bool, error) {
+	if ctx.Err() != nil {
+		return false, errLoopCancelled
+	}
+	defer heartbeat()
+
+	// 丢弃上一阶段遗留的跳过请求，避免连续请求顺带跳过下一阶段
+	drain(skipCh)
+
+	setCurrentTask(phase, duration)
+
+	// elapsed 累计本阶段已经计时的部分（不含快速专注和暂停），被打断后从这里继续
+	elapsed := time.Duration(0)
+	segmentStart := time.Now()
+	for {
+		timer := time.NewTimer(duration - elapsed)
+		select {
+		case <-timer.C:
+			recordPhaseEnd(phase, duration, elapsed+time.Since(segmentStart), false)
+			return false, nil
+		case <-skipCh:
+			timer.Stop()
+			return endPhaseEarly(phase, duration, elapsed+time.Since(segmentStart)), nil
+		case <-ctx.Done():
+			timer.Stop()
+			return false, errLoopCancelled
+		case <-pauseCh:
+			timer.Stop()
+			// 已用时间只在暂停时刻累加一次，继续后的起点就是继续的时刻，反复暂停不会累积误差
+			pausedAt := time.Now()
+			elapsed += pausedAt.Sub(segmentStart)
+			if elapsed > duration {
+				elapsed = duration
+			}
+
+			skipped, resumedAt, err := pauseWait(ctx, pausedAt)
+			if err != nil {
+				return false, err
+			}
+			if skipped {
+				return endPhaseEarly(phase, duration, elapsed), nil
+			}
+
+			// 从暂停的位置继续，进度条保持暂停前的位置
+			segmentStart = resumedAt
+			setCurrentTaskAt(phase, duration, segmentStart.Add(-elapsed))
+		case d := <-quickFocusCh:
+			timer.Stop()
+			elapsed += time.Since(segmentStart)
+			if elapsed > duration {
+				elapsed = duration
+			}
+
+			if err := runQuickFocus(ctx, d); err != nil {
+				return false, err
+			}
+
+			// 恢复被打断的阶段，进度条保持打断前的位置
+			segmentStart = time.Now()
+			setCurrentTaskAt(phase, duration, segmentStart.Add(-elapsed))
+		}
+	}
 }
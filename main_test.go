@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// useTestConfig 补全默认值后发布 c 作为当前配置和基础配置，关闭全部提示音，测试结束时恢复原来的快照
+func useTestConfig(t *testing.T, c Config) *Config {
+	t.Helper()
+	prev, prevBase := currentConfig(), currentBaseConfig()
+	t.Cleanup(func() {
+		configSnap.Store(prev)
+		baseConfigSnap.Store(prevBase)
+	})
+
+	applyDefaults(&c)
+	c.Sounds = map[string]string{}
+	for _, ev := range allEvents {
+		c.Sounds[ev] = ""
+	}
+	setBaseConfig(c)
+	setConfig(c)
+	return currentConfig()
+}
+
+// testSchedule 返回一份合法的计时配置：60-90 秒的小循环，每个中循环 5 分钟
+func testSchedule() Config {
+	return Config{
+		MicroBaseS:    75,
+		MicroOffsetS:  15,
+		MicroRestS:    10,
+		MesoDurationM: 5,
+		MesoRestM:     1,
+		MesoCount:     2,
+		MacroRestM:    5,
+		MacroCount:    1,
+	}
+}
+
+func TestRunMesoCycleCancelled(t *testing.T) {
+	useTestConfig(t, testSchedule())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- runMesoCycle(ctx, 1, false) }()
+
+	// 等到第一个小循环开始计时后再取消
+	deadline := time.Now().Add(time.Second)
+	for getPhase() != phaseMicroFocus && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, errLoopCancelled) {
+			t.Fatalf("runMesoCycle 返回 %v，应为 errLoopCancelled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("取消后 runMesoCycle 没有返回")
+	}
+}
+
+func TestRunMacroCycleCancelledDuringPause(t *testing.T) {
+	useTestConfig(t, testSchedule())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- runMacroCycle(ctx, true) }()
+
+	deadline := time.Now().Add(time.Second)
+	for getPhase() != phaseMicroFocus && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if err := requestPause(); err != nil {
+		t.Fatalf("暂停失败: %v", err)
+	}
+	for !isPaused() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, errLoopCancelled) {
+			t.Fatalf("runMacroCycle 返回 %v，应为 errLoopCancelled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("取消后 runMacroCycle 没有返回")
+	}
+	if isPaused() {
+		t.Error("取消后仍处于暂停状态")
+	}
+}
+
+func TestWaitCancelledBeforeStart(t *testing.T) {
+	useTestConfig(t, testSchedule())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := wait(ctx, phaseMicroRest, time.Hour); !errors.Is(err, errLoopCancelled) {
+		t.Fatalf("wait 返回 %v，应为 errLoopCancelled", err)
+	}
+	if _, err := waitForAck(ctx, phaseReady, 0); !errors.Is(err, errLoopCancelled) {
+		t.Fatalf("waitForAck 返回 %v，应为 errLoopCancelled", err)
+	}
+}
+
+func TestWithFallbackMicro(t *testing.T) {
+	c := useTestConfig(t, testSchedule())
+
+	got, fallback := withFallbackMicro(c, nil)
+	if !fallback {
+		t.Error("空规划没有使用替代小循环")
+	}
+	if len(got) != 1 || got[0] != c.microBase() {
+		t.Errorf("空规划得到 %v，应为一个 %v 的小循环", got, c.microBase())
+	}
+
+	plan := []time.Duration{time.Minute, 80 * time.Second}
+	got, fallback = withFallbackMicro(c, plan)
+	if fallback || len(got) != len(plan) {
+		t.Errorf("非空规划被替换为 %v", got)
+	}
+}
+
+func TestPlanMesoScheduleSameSeed(t *testing.T) {
+	c := useTestConfig(t, testSchedule())
+
+	first := planMesoSchedule(c, c.mesoDuration(), rand.New(rand.NewSource(42)).Intn)
+	second := planMesoSchedule(c, c.mesoDuration(), rand.New(rand.NewSource(42)).Intn)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("相同种子得到不同的规划:\n%v\n%v", first, second)
+	}
+}
+
+// TestPlanMesoSchedulePathological 目标极短或小循环远长于目标时，
+// 仍至少规划一个小循环，且每个时长都在 [基础-偏移, 基础+偏移] 之内
+func TestPlanMesoSchedulePathological(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		base, offset int
+		target       time.Duration
+	}{
+		{"目标为 0", 75, 15, 0},
+		{"目标 1 秒", 75, 15, time.Second},
+		{"目标不足一秒", 75, 15, 500 * time.Millisecond},
+		{"基础时间远长于目标", 3600, 0, time.Minute},
+		{"基础时间远长于目标且有偏移", 3600, 3599, time.Minute},
+		{"最短 1 秒", 1, 0, 5 * time.Minute},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := testSchedule()
+			c.MicroBaseS, c.MicroOffsetS = tc.base, tc.offset
+			cfg := useTestConfig(t, c)
+
+			minDur := time.Duration(tc.base-tc.offset) * time.Second
+			maxDur := time.Duration(tc.base+tc.offset) * time.Second
+			got := planMesoSchedule(cfg, tc.target, rand.New(rand.NewSource(1)).Intn)
+			if len(got) == 0 {
+				t.Fatal("没有规划出任何小循环")
+			}
+			for i, d := range got {
+				if d <= 0 || d < minDur || d > maxDur {
+					t.Errorf("第 %d 个小循环时长 %v 不在 [%v, %v] 之内", i+1, d, minDur, maxDur)
+				}
+			}
+		})
+	}
+}
+
+// skipThroughMeso 运行一个中循环，每个阶段一开始计时就跳过，返回各阶段开始时的计时状态
+func skipThroughMeso(t *testing.T, isLast bool) []timingSnapshot {
+	t.Helper()
+	setCurrentTask(phaseIdle, 0)
+	t.Cleanup(func() { setCurrentTask(phaseIdle, 0) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- runMesoCycle(ctx, 1, isLast) }()
+
+	var seen []timingSnapshot
+	lastStart := int64(0)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("runMesoCycle 返回 %v", err)
+			}
+			if len(seen) == 0 {
+				t.Fatal("没有观察到任何阶段")
+			}
+			return seen
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("中循环没有按时结束")
+		}
+		s := readTiming()
+		if s.cStart == lastStart || s.phase == phaseIdle {
+			time.Sleep(50 * time.Microsecond)
+			continue
+		}
+		// 新的阶段开始计时：记下它的状态后立即跳过
+		lastStart = s.cStart
+		seen = append(seen, s)
+		select {
+		case skipCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// microPhasesTotal 返回小循环专注和小休息阶段的时长之和
+func microPhasesTotal(seen []timingSnapshot) time.Duration {
+	var total time.Duration
+	for _, s := range seen {
+		if s.phase == phaseMicroFocus || s.phase == phaseMicroRest {
+			total += time.Duration(s.cDur)
+		}
+	}
+	return total
+}
+
+// TestMesoTotalMatchesWaitedPhases 进度条显示的中循环总时长应等于各阶段计时时长之和
+func TestMesoTotalMatchesWaitedPhases(t *testing.T) {
+	useTestConfig(t, testSchedule())
+
+	seen := skipThroughMeso(t, true)
+	if displayed, waited := time.Duration(seen[0].mDur), microPhasesTotal(seen); displayed != waited {
+		t.Errorf("中循环显示总时长 %v，%d 个阶段合计 %v", displayed, len(seen), waited)
+	}
+}
+
+// TestFinalMesoTotalMatchesDuration 最后一个中循环按调整后的目标规划，显示的总时长等于其中各阶段之和，
+// 不包含之后的过渡；过渡期间仍显示走满的中循环进度条
+func TestFinalMesoTotalMatchesDuration(t *testing.T) {
+	c := testSchedule()
+	c.FinalMesoPauseS = 3
+	c.lastMesoAdjust = 2 * time.Minute
+	cfg := useTestConfig(t, c)
+
+	seen := skipThroughMeso(t, true)
+	displayed := time.Duration(seen[0].mDur)
+	if waited := microPhasesTotal(seen); displayed != waited {
+		t.Errorf("最后一个中循环显示总时长 %v，各阶段合计 %v", displayed, waited)
+	}
+	if target := mesoTargetDuration(cfg, true); displayed < target-cfg.microRest() {
+		t.Errorf("最后一个中循环显示总时长 %v，没有按调整后的目标 %v 规划", displayed, target)
+	}
+
+	last := seen[len(seen)-1]
+	if last.phase != phaseTransition || last.cDur != int64(3*time.Second) {
+		t.Fatalf("最后一个阶段是 %s（%v），应为 3 秒的过渡", last.phase, time.Duration(last.cDur))
+	}
+	if !last.inMeso || last.mDur != int64(displayed) {
+		t.Errorf("过渡期间中循环进度条 显示=%v 总时长=%v，应保留 %v", last.inMeso, time.Duration(last.mDur), displayed)
+	}
+}
+
+// TestPlanMesoScheduleLargeMeso 按上限允许的最长中循环规划，小循环数量和耗时都应有界，总时长不溢出
+func TestPlanMesoScheduleLargeMeso(t *testing.T) {
+	for _, tc := range []struct {
+		name               string
+		base, offset, rest int
+	}{
+		{"小循环数量接近上限", 9, 0, 0},
+		{"带休息和偏移", 20, 10, 5},
+		{"小循环长达一天", int(maxPhaseDuration / time.Second), 0, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := testSchedule()
+			c.MesoDurationM = int(maxPhaseDuration / time.Minute)
+			c.MicroBaseS, c.MicroOffsetS, c.MicroRestS = tc.base, tc.offset, tc.rest
+			applyDefaults(&c)
+			if err := validateConfig(c); err != nil {
+				t.Fatalf("上限内的配置被拒绝: %v", err)
+			}
+			cfg := useTestConfig(t, c)
+
+			start := time.Now()
+			micros := planMesoSchedule(cfg, cfg.mesoDuration(), rand.New(rand.NewSource(1)).Intn)
+			total := mesoTotalDuration(cfg, micros)
+			if took := time.Since(start); took > time.Second {
+				t.Errorf("规划用了 %v", took)
+			}
+			if len(micros) == 0 || len(micros) > maxMicrosPerMeso {
+				t.Errorf("规划出 %d 个小循环，上限 %d", len(micros), maxMicrosPerMeso)
+			}
+			if total < cfg.mesoDuration()-cfg.microRest() || total > 2*maxPhaseDuration {
+				t.Errorf("中循环总时长 %v，目标 %v", total, cfg.mesoDuration())
+			}
+		})
+	}
+}
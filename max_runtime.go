@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// 距离最长运行时间还剩这些时间时各提示一次
+var runtimeLimitWarnings = []time.Duration{10 * time.Minute, time.Minute}
+
+var timerDoneOnce sync.Once
+
+// closeTimerDone 标记计时器循环结束；正常结束和达到最长运行时间可能同时发生，只关闭一次
+func closeTimerDone() {
+	timerDoneOnce.Do(func() { close(timerDone) })
+}
+
+// startRuntimeLimitIfNeeded 在配置了最长运行小时时开始计时，到时结束计时器循环并退出
+func startRuntimeLimitIfNeeded() {
+	cfg := currentConfig()
+	if cfg.MaxRuntimeH <= 0 {
+		return
+	}
+	go runRuntimeLimit(time.Now(), time.Duration(cfg.MaxRuntimeH)*time.Hour)
+}
+
+func runRuntimeLimit(start time.Time, limit time.Duration) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("最长运行时间计时崩溃: %v", r)
+		}
+	}()
+	deadline := start.Add(limit)
+	log.Printf("最长运行时间: %v，将在 %s 自动退出", limit, deadline.Format("01-02 15:04"))
+
+	for _, before := range runtimeLimitWarnings {
+		if before >= limit || !sleepUntil(deadline.Add(-before)) {
+			continue
+		}
+		if timerFinished() {
+			return
+		}
+		progress("max_runtime_warning", before, ">>> 还有 %v 达到最长运行时间，届时将自动退出", before)
+	}
+
+	sleepUntil(deadline)
+	if timerFinished() {
+		return
+	}
+	stopForRuntimeLimit(limit)
+}
+
+// sleepUntil 等到指定时间，已经过了则立即返回 false
+func sleepUntil(t time.Time) bool {
+	d := time.Until(t)
+	if d <= 0 {
+		return false
+	}
+	time.Sleep(d)
+	return true
+}
+
+// stopForRuntimeLimit 不论当前处于哪个阶段，都停止计时器循环并按正常结束的流程退出
+func stopForRuntimeLimit(limit time.Duration) {
+	loopCancelMu.Lock()
+	if loopCancel != nil {
+		loopCancel()
+	}
+	loopCancelMu.Unlock()
+
+	clearMesoTask()
+	setCurrentTask(phaseDone, 0)
+	sessionElapsed = time.Since(sessionStart)
+
+	setProgressIndex(0, 0)
+	msg := fmt.Sprintf("已运行 %v，达到最长运行时间 (最长运行小时 = %d)，自动退出", limit, currentConfig().MaxRuntimeH)
+	progress("max_runtime_exit", sessionElapsed, ">>> %s", msg)
+	log.Println(msg)
+	playEvent(eventSessionEnd)
+	closeTimerDone()
+}
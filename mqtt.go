@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	mqttTimeout = 5 * time.Second
+	// mqttQueueSize 是等待发布的消息上限，服务器长时间无响应时多出的消息丢弃
+	mqttQueueSize = 32
+)
+
+var (
+	// mqttQueue 把全部发布交给同一个协程依次进行。客户端 ID 固定，
+	// 同时存在两个连接时服务器会断开先建立的那个，消息就丢了
+	mqttOnce  sync.Once
+	mqttQueue = make(chan mqttMessage, mqttQueueSize)
+)
+
+type mqttMessage struct {
+	event   string
+	payload []byte
+}
+
+// mqttPayload 是发布到 MQTT 主题的消息内容
+type mqttPayload struct {
+	Event         string  `json:"event"`
+	Phase         string  `json:"phase"`
+	ActualSeconds float64 `json:"actual_seconds"` // 刚结束的阶段实际用时
+	Timestamp     string  `json:"timestamp"`
+}
+
+// sendMQTT 把事件发布到配置的 MQTT 服务器（QoS 0），供手机、手表等伴侣应用振动提醒；
+// 未配置时不做任何事。发布在后台依次进行，每次发布单独连接，不维护长连接
+func sendMQTT(event string) {
+	if currentConfig().MQTTAddress == "" {
+		return
+	}
+	payload, _ := json.Marshal(mqttPayload{
+		Event:         event,
+		Phase:         getPhase(),
+		ActualSeconds: getLastCycleTiming().Actual.Seconds(),
+		Timestamp:     time.Now().Format(time.RFC3339),
+	})
+
+	mqttOnce.Do(func() { go runMQTTPublisher() })
+	select {
+	case mqttQueue <- mqttMessage{event, payload}:
+	default:
+		log.Printf("MQTT 发布队列已满，丢弃 %s", event)
+	}
+}
+
+// runMQTTPublisher 依次发布队列中的消息，同一时刻最多只有一个连接
+func runMQTTPublisher() {
+	for msg := range mqttQueue {
+		publishQueued(msg)
+	}
+}
+
+func publishQueued(msg mqttMessage) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("MQTT 发布崩溃: %v", r)
+		}
+	}()
+	if err := publishMQTT(currentConfig(), msg.payload); err != nil {
+		log.Printf("MQTT 发布失败 (%s): %v", msg.event, err)
+	}
+}
+
+// publishMQTT 按 MQTT 3.1.1 连接、发布一条消息后断开
+func publishMQTT(cfg *Config, payload []byte) error {
+	conn, err := net.DialTimeout("tcp", cfg.MQTTAddress, mqttTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(mqttTimeout))
+
+	if _, err := conn.Write(mqttConnectPacket(fmt.Sprintf("fanqiezhong-%d", os.Getpid()), cfg.MQTTUsername, cfg.MQTTPassword)); err != nil {
+		return err
+	}
+	ack := make([]byte, 4)
+	if _, err := io.ReadFull(conn, ack); err != nil {
+		return fmt.Errorf("读取 CONNACK 失败: %v", err)
+	}
+	if ack[0] != 0x20 || ack[3] != 0 {
+		return fmt.Errorf("服务器拒绝连接 (返回码 %d)", ack[3])
+	}
+
+	if _, err := conn.Write(mqttPublishPacket(cfg.MQTTTopic, payload)); err != nil {
+		return err
+	}
+	_, err = conn.Write([]byte{0xE0, 0x00}) // DISCONNECT
+	return err
+}
+
+// mqttConnectPacket 编码 CONNECT 报文：清除会话，保活 60 秒，可选用户名和密码
+func mqttConnectPacket(clientID, username, password string) []byte {
+	var body bytes.Buffer
+	writeMQTTString(&body, "MQTT")
+	body.WriteByte(4) // 协议级别 3.1.1
+
+	flags := byte(0x02)
+	if username != "" {
+		flags |= 0x80
+		if password != "" {
+			flags |= 0x40
+		}
+	}
+	body.WriteByte(flags)
+	body.Write([]byte{0x00, 60})
+
+	writeMQTTString(&body, clientID)
+	if username != "" {
+		writeMQTTString(&body, username)
+		if password != "" {
+			writeMQTTString(&body, password)
+		}
+	}
+	return mqttPacket(0x10, body.Bytes())
+}
+
+// mqttPublishPacket 编码 QoS 0 的 PUBLISH 报文
+func mqttPublishPacket(topic string, payload []byte) []byte {
+	var body bytes.Buffer
+	writeMQTTString(&body, topic)
+	body.Write(payload)
+	return mqttPacket(0x30, body.Bytes())
+}
+
+// mqttPacket 加上固定报头：类型字节和变长编码的剩余长度
+func mqttPacket(header byte, body []byte) []byte {
+	b := []byte{header}
+	n := len(body)
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if n == 0 {
+			break
+		}
+	}
+	return append(b, body...)
+}
+
+// writeMQTTString 写入带 2 字节长度前缀的 UTF-8 字符串
+func writeMQTTString(b *bytes.Buffer, s string) {
+	b.WriteByte(byte(len(s) >> 8))
+	b.WriteByte(byte(len(s)))
+	b.WriteString(s)
+}
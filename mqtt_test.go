@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestMQTTPublishesOneConnectionAtATime 在短时间内发出多个事件，
+// 服务器同一时刻只应看到一个连接，并且收到全部消息
+func TestMQTTPublishesOneConnectionAtATime(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// waiting 是已接受、还没有收到 CONNACK 的连接数。依次发布时客户端收到 CONNACK 之前
+	// 不会建立下一个连接，接受新连接时 waiting 大于 0 就说明有并发的连接
+	var waiting, overlaps, total int32
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if atomic.LoadInt32(&waiting) > 0 {
+				atomic.AddInt32(&overlaps, 1)
+			}
+			atomic.AddInt32(&waiting, 1)
+			go func() {
+				defer conn.Close()
+				time.Sleep(20 * time.Millisecond)
+				atomic.AddInt32(&waiting, -1)
+				conn.Write([]byte{0x20, 0x02, 0x00, 0x00}) // CONNACK
+				io.Copy(io.Discard, conn)
+				atomic.AddInt32(&total, 1)
+			}()
+		}
+	}()
+
+	c := testSchedule()
+	c.MQTTAddress = ln.Addr().String()
+	useTestConfig(t, c)
+
+	const events = 10
+	for i := 0; i < events; i++ {
+		sendMQTT(eventMicroEnd)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&total) < events && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := atomic.LoadInt32(&total); got != events {
+		t.Fatalf("服务器收到 %d 个连接，应为 %d", got, events)
+	}
+	if got := atomic.LoadInt32(&overlaps); got != 0 {
+		t.Errorf("有 %d 个连接与其他连接同时存在，应依次发布", got)
+	}
+}
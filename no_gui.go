@@ -9,5 +9,5 @@ import (
 
 func startGUIOrBlock() {
 	log.Println("运行在终端模式（阻塞中）")
-	select {}
+	<-timerDone
 }
@@ -3,6 +3,29 @@
 
 package main
 
+import (
+	"context"
+	"errors"
+)
+
 func startWebServerIfNeeded() {
 	// 无 Web 服务器
 }
+
+func stopWebServer(ctx context.Context) error {
+	return nil
+}
+
+func restartWebServer() {}
+
+func webSelfChecks() []selfCheck {
+	return nil
+}
+
+func webSetupAvailable() bool {
+	return false
+}
+
+func runWebSetup() (Config, error) {
+	return Config{}, errors.New("未包含 Web 功能")
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"log"
+)
+
+// notificationText 各事件的系统通知内容，没有列出的事件不发通知
+var notificationText = map[string]string{
+	eventMicroEnd:      "小循环结束，休息一下",
+	eventMicroRestEnd:  "小休息结束，继续专注",
+	eventMesoEnd:       "中循环结束，开始中循环休息",
+	eventMesoRestEnd:   "中循环休息结束，开始下一个中循环",
+	eventMacroEnd:      "大循环结束",
+	eventMacroRestEnd:  "大循环休息结束",
+	eventQuickFocusEnd: "快速专注结束，回到原计划",
+	eventCooldownEnd:   "冷却休息结束",
+}
+
+// sendNotification 在开启 系统通知 时为事件弹出系统通知。通知在后台发送，
+// 没有通知服务（如无桌面的服务器）时只记录日志
+func sendNotification(event string) {
+	cfg := currentConfig()
+	if !cfg.Notifications {
+		return
+	}
+	body, ok := notificationText[event]
+	if event == eventSessionEnd {
+		body, ok = cfg.SessionEndMessage, true
+	}
+	if !ok {
+		return
+	}
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("系统通知崩溃: %v", r)
+			}
+		}()
+		if err := notify("番茄钟", body); err != nil {
+			log.Printf("系统通知失败 (%s): %v", event, err)
+		}
+	}()
+}
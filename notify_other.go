@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"runtime"
+)
+
+// notify 使用系统自带的通知命令：macOS 为 osascript，其他系统为 notify-send
+func notify(title, body string) error {
+	if runtime.GOOS == "darwin" {
+		// 文本作为参数传入，避免被当作脚本解析
+		return exec.Command("osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title, body).Run()
+	}
+	if _, err := exec.LookPath("notify-send"); err != nil {
+		return errors.New("未找到通知命令 notify-send")
+	}
+	return exec.Command("notify-send", title, body).Run()
+}
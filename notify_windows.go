@@ -0,0 +1,9 @@
+package main
+
+// notify 通过 PowerShell 在任务栏通知区域弹出气泡通知（Windows 10/11 显示为系统通知）
+func notify(title, body string) error {
+	const script = `Add-Type -AssemblyName System.Windows.Forms; Add-Type -AssemblyName System.Drawing; ` +
+		`$n = New-Object System.Windows.Forms.NotifyIcon; $n.Icon = [System.Drawing.SystemIcons]::Information; $n.Visible = $true; ` +
+		`$n.ShowBalloonTip(5000, $env:FANQIE_NOTIFY_TITLE, $env:FANQIE_NOTIFY_BODY, 'Info'); Start-Sleep -Seconds 6; $n.Dispose()`
+	return runPowerShell(script, "FANQIE_NOTIFY_TITLE="+title, "FANQIE_NOTIFY_BODY="+body)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log"
+	"math"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	oscOnce sync.Once
+	oscConn net.Conn
+)
+
+// sendOSC 在阶段切换时把阶段名和阶段时长（秒）以 OSC 消息发送到配置的 UDP 地址，未配置时不做任何事
+func sendOSC(phase string, duration time.Duration) {
+	cfg := currentConfig()
+	if cfg.OSCAddress == "" {
+		return
+	}
+	oscOnce.Do(func() {
+		conn, err := net.Dial("udp", cfg.OSCAddress)
+		if err != nil {
+			log.Printf("OSC 地址无效 %s: %v", cfg.OSCAddress, err)
+			return
+		}
+		oscConn = conn
+	})
+	if oscConn == nil {
+		return
+	}
+	msg := encodeOSC(cfg.OSCPath, phase, float32(duration.Seconds()))
+	if _, err := oscConn.Write(msg); err != nil {
+		log.Printf("发送 OSC 消息失败: %v", err)
+	}
+}
+
+// encodeOSC 编码一条 OSC 1.0 消息，参数只支持 string 和 float32
+func encodeOSC(address string, args ...interface{}) []byte {
+	var b bytes.Buffer
+	writeOSCString(&b, address)
+
+	tags := ","
+	for _, a := range args {
+		switch a.(type) {
+		case string:
+			tags += "s"
+		case float32:
+			tags += "f"
+		}
+	}
+	writeOSCString(&b, tags)
+
+	for _, a := range args {
+		switch v := a.(type) {
+		case string:
+			writeOSCString(&b, v)
+		case float32:
+			binary.Write(&b, binary.BigEndian, math.Float32bits(v))
+		}
+	}
+	return b.Bytes()
+}
+
+// writeOSCString 写入以 0 结尾并补齐到 4 字节边界的字符串
+func writeOSCString(b *bytes.Buffer, s string) {
+	b.WriteString(s)
+	pad := 4 - len(s)%4
+	b.Write(make([]byte, pad))
+}
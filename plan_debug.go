@@ -0,0 +1,53 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// mesoPlanInfo 记录当前中循环的规划结果，开启 调试 时输出，用于排查时间安排看起来不对的原因
+type mesoPlanInfo struct {
+	MesoIndex        int       `json:"meso_index"`
+	TargetSeconds    float64   `json:"target_seconds"`    // 中循环目标时长
+	MinSeconds       float64   `json:"min_seconds"`       // 小循环随机范围下限
+	MaxSeconds       float64   `json:"max_seconds"`       // 小循环随机范围上限
+	Micros           int       `json:"micros"`            // 规划出的小循环数
+	DurationsSeconds []float64 `json:"durations_seconds"` // 各小循环时长
+	PlannedSeconds   float64   `json:"planned_seconds"`   // 含小休息的实际计划时长
+	OvershootSeconds float64   `json:"overshoot_seconds"` // 计划时长超出目标的部分（不足时为负）
+	Fallback         bool      `json:"fallback"`          // 规划为空，改用一个基础时长的小循环
+	Seed             int64     `json:"seed,omitempty"`    // 配置的随机种子
+}
+
+var lastMesoPlan atomic.Pointer[mesoPlanInfo]
+
+// recordMesoPlan 保存本中循环的规划结果，开启 调试 时输出一行规划细节
+func recordMesoPlan(index int, target time.Duration, durations []time.Duration, planned time.Duration, fallback bool) {
+	cfg := currentConfig()
+	info := &mesoPlanInfo{
+		MesoIndex:      index,
+		TargetSeconds:  target.Seconds(),
+		MinSeconds:     (cfg.microBase() - cfg.microOffset()).Seconds(),
+		MaxSeconds:     (cfg.microBase() + cfg.microOffset()).Seconds(),
+		Micros:         len(durations),
+		PlannedSeconds: planned.Seconds(),
+		Fallback:       fallback,
+		Seed:           cfg.RandomSeed,
+	}
+	info.OvershootSeconds = info.PlannedSeconds - info.TargetSeconds
+	for _, d := range durations {
+		info.DurationsSeconds = append(info.DurationsSeconds, d.Seconds())
+	}
+	lastMesoPlan.Store(info)
+
+	if cfg.Debug {
+		progress("meso_plan_debug", planned, "  >> 规划细节: 目标 %v，小循环 %v-%v 共 %d 个，含小休息计划 %v（超出目标 %v），时长 %v",
+			target, cfg.microBase()-cfg.microOffset(), cfg.microBase()+cfg.microOffset(),
+			len(durations), planned, planned-target, durations)
+	}
+}
+
+// currentMesoPlan 返回最近一次的规划结果，还没有开始中循环时为 nil
+func currentMesoPlan() *mesoPlanInfo {
+	return lastMesoPlan.Load()
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// runPowerShell 在隐藏的 PowerShell 中运行脚本并等待结束。
+// 文本通过环境变量（形如 "NAME=value"）传入，避免被当作脚本解析；窗口版不弹出控制台窗口
+func runPowerShell(script string, env ...string) error {
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true, CreationFlags: 0x08000000} // CREATE_NO_WINDOW
+	return cmd.Run()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// withPreset 返回叠加当前预设后的配置；没有选择预设时原样返回
+func withPreset(base Config) Config {
+	c := base
+	if o, ok := base.Presets[base.ActivePreset]; ok {
+		o.apply(&c)
+	}
+	return c
+}
+
+// presetLabel 返回用于输出的预设名称
+func presetLabel(name string) string {
+	if name == "" {
+		return "（不使用预设）"
+	}
+	return name
+}
+
+// presetNames 按名称排序返回全部预设
+func presetNames(c Config) []string {
+	names := make([]string, 0, len(c.Presets))
+	for name := range c.Presets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// validatePresets 检查当前预设存在，并确认每个预设（不只是当前的）叠加后每一天的配置都有效
+func validatePresets(base Config) error {
+	if _, ok := base.Presets[base.ActivePreset]; base.ActivePreset != "" && !ok {
+		return fmt.Errorf("当前预设 %q 不在 预设 中", base.ActivePreset)
+	}
+	for _, name := range presetNames(base) {
+		if name == "" {
+			return fmt.Errorf("预设名称不能为空")
+		}
+		c := base
+		c.ActivePreset = name
+		if err := validateWeekdays(c); err != nil {
+			return fmt.Errorf("预设 %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// queuePreset 换用指定的预设，与修改配置文件一样在下一个大循环开始时生效；
+// 空名称表示不使用预设。已有等待生效的新配置时在它的基础上切换
+func queuePreset(name string) error {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+
+	c := *currentBaseConfig()
+	if pendingConfig != nil {
+		c = *pendingConfig
+	}
+	if _, ok := c.Presets[name]; name != "" && !ok {
+		return fmt.Errorf("没有名为 %q 的预设", name)
+	}
+	c.ActivePreset = name
+	pendingConfig = &c
+	return nil
+}
+
+// presetState 在同一次加锁中返回基础配置快照和等待生效的预设名称，
+// 没有等待生效的配置时 ok 为 false
+func presetState() (base *Config, pending string, ok bool) {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	base = currentBaseConfig()
+	if pendingConfig == nil {
+		return base, "", false
+	}
+	return base, pendingConfig.ActivePreset, true
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// selfCheck 是自检中的单个检查项
+type selfCheck struct {
+	name string
+	run  func() error
+}
+
+// runSelfTest 依次执行所有检查并输出报告，返回进程退出码
+func runSelfTest() int {
+	checks := []selfCheck{
+		{"配置文件", checkConfig},
+		{"提示音文件", checkSounds},
+		{"音频设备", initSpeaker},
+	}
+	// Web 相关检查只在包含 'web' 标签时存在
+	checks = append(checks, webSelfChecks()...)
+
+	fmt.Println("番茄钟自检:")
+	failed := 0
+	for _, c := range checks {
+		if err := c.run(); err != nil {
+			failed++
+			fmt.Printf("  [失败] %s: %v\n", c.name, err)
+		} else {
+			fmt.Printf("  [通过] %s\n", c.name)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("自检完成: %d 项失败\n", failed)
+		return 1
+	}
+	fmt.Println("自检完成: 全部通过")
+	return 0
+}
+
+func checkConfig() error {
+	c, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	applyDefaults(&c)
+	// 之后的检查项（提示音、Web 端口）使用这份配置
+	setConfig(c)
+	// 与启动时相同的校验
+	if err := validateConfig(c); err != nil {
+		return err
+	}
+	if err := validateWeekdays(c); err != nil {
+		return err
+	}
+	return validatePresets(c)
+}
+
+func checkSounds() error {
+	var errs []error
+	for _, path := range soundFiles() {
+		streamer, _, err := decodeSound(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		streamer.Close()
+	}
+	return errors.Join(errs...)
+}
+
+// soundFiles 返回运行中会用到的全部提示音文件（去重）
+func soundFiles() []string {
+	var files []string
+	seen := map[string]bool{}
+	for _, ev := range allEvents {
+		f := eventSound(ev)
+		if f == "" || seen[f] {
+			continue
+		}
+		seen[f] = true
+		files = append(files, f)
+	}
+	return files
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sync/atomic"
+)
+
+// 会话 ID 的生成方式
+const (
+	sessionIDOff     = ""
+	sessionIDProcess = "process" // 每次启动生成一个
+	sessionIDMacro   = "macro"   // 每个大循环开始时重新生成
+)
+
+// sessionID 是当前会话 ID，关闭时为空字符串
+var sessionID atomic.Value
+
+func isValidSessionIDMode(mode string) bool {
+	switch mode {
+	case sessionIDOff, sessionIDProcess, sessionIDMacro:
+		return true
+	}
+	return false
+}
+
+// newSessionID 生成类似 git 短提交号的 7 位十六进制随机 ID
+func newSessionID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)[:7]
+}
+
+func currentSessionID() string {
+	id, _ := sessionID.Load().(string)
+	return id
+}
+
+// setSessionID 换用新的会话 ID，log 包输出的每一行都以它开头，便于筛选同一次运行的日志
+func setSessionID(id string) {
+	sessionID.Store(id)
+	log.SetPrefix("[" + id + "] ")
+}
+
+// startSessionIDIfNeeded 在开启会话ID时为本次启动生成 ID
+func startSessionIDIfNeeded() {
+	if currentConfig().SessionIDMode == sessionIDOff {
+		return
+	}
+	setSessionID(newSessionID())
+}
+
+// renewSessionIDForMacro 在按大循环生成时换用新的 ID；第一个大循环沿用启动时的 ID
+func renewSessionIDForMacro(first bool) {
+	if currentConfig().SessionIDMode != sessionIDMacro || first {
+		return
+	}
+	setSessionID(newSessionID())
+	log.Println("新的会话 ID")
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// 按当前配置估算的一个大循环和整个会话的计划时长，配置变化时重新计算
+	plannedMacroNano   int64
+	plannedSessionNano int64 // 无限循环时为 -1
+)
+
+// refreshSessionPlan 重新估算计划时长。小循环时长是随机的，这里与 -chart 一样使用固定种子规划，
+// 每个中循环的总时长只在小休息的次数上有差别，估算与实际相差通常不超过几次小休息
+func refreshSessionPlan() {
+	cfg := currentConfig()
+	macro := plannedMacroDuration(cfg, rand.New(rand.NewSource(chartSeed)))
+	atomic.StoreInt64(&plannedMacroNano, int64(macro))
+
+	session := time.Duration(-1)
+	if cfg.MacroCount > 0 {
+		// 最后一个大循环之后没有大循环休息和间隔
+		between := cfg.macroRest() + cfg.macroGap()
+		session = time.Duration(cfg.MacroCount)*(macro+between) - between
+	}
+	atomic.StoreInt64(&plannedSessionNano, int64(session))
+}
+
+// plannedMacroDuration 计算一个大循环的计划时长：全部中循环（含小休息）、中循环休息和过渡时间
+func plannedMacroDuration(c *Config, rng *rand.Rand) time.Duration {
+	total := time.Duration(0)
+	for i := 0; i < c.MesoCount; i++ {
+		total += mesoTotalDuration(c, planMesoSchedule(c, mesoTargetDuration(c, i == c.MesoCount-1), rng.Intn))
+	}
+	if c.MesoCount > 1 {
+		total += time.Duration(c.MesoCount-1) * c.mesoRest()
+	}
+	return total + time.Duration(c.FinalMesoPauseS)*time.Second
+}
+
+// plannedDurations 返回一个大循环和整个会话的计划时长；无限循环时 sessionOK 为 false
+func plannedDurations() (macro, session time.Duration, sessionOK bool) {
+	macro = time.Duration(atomic.LoadInt64(&plannedMacroNano))
+	session = time.Duration(atomic.LoadInt64(&plannedSessionNano))
+	return macro, session, session >= 0
+}
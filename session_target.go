@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// fitSessionTarget 按目标会话时长推算每个大循环的中循环组数。有限次大循环时目标是整个会话
+// （扣除大循环之间的休息和间隔后平均分给每个大循环），无限循环时目标是每个大循环。
+// 放不下整数个中循环时，余下的时间超过一个中循环（含休息）的一半就多安排一个并缩短它，
+// 否则延长最后一个中循环，返回中循环组数和最后一个中循环的增减
+func fitSessionTarget(c Config) (int, time.Duration, error) {
+	target := c.targetSession()
+	budget := target
+	if c.MacroCount > 0 {
+		between := c.macroRest() + c.macroGap()
+		budget = (target - time.Duration(c.MacroCount-1)*between) / time.Duration(c.MacroCount)
+	}
+
+	meso, rest := c.mesoDuration(), c.mesoRest()
+	work := budget - time.Duration(c.FinalMesoPauseS)*time.Second
+	if work < meso {
+		return 0, 0, fmt.Errorf("目标会话时长 %v 不足以让每个大循环安排一个中循环", target)
+	}
+
+	n := int((work + rest) / (meso + rest))
+	left := work - time.Duration(n)*meso - time.Duration(n-1)*rest
+	shortest := c.microBase() - c.microOffset()
+	if left > (meso+rest)/2 && meso+left-(meso+rest) >= shortest {
+		n++
+		left -= meso + rest
+	}
+
+	if n > maxCycleCount {
+		return 0, 0, fmt.Errorf("目标会话时长过长，推算出的中循环组数超过 %d", maxCycleCount)
+	}
+	if (meso+left)/shortest > maxMicrosPerMeso {
+		return 0, 0, fmt.Errorf("目标会话时长推算出的最后一个中循环过长，每个中循环最多 %d 个小循环", maxMicrosPerMeso)
+	}
+	return n, left, nil
+}
+
+// applySessionTarget 配置了目标会话时长时，用推算结果覆盖中循环组数；配置已通过校验
+func applySessionTarget(c *Config) {
+	c.lastMesoAdjust = 0
+	if c.targetSession() <= 0 {
+		return
+	}
+	n, adjust, err := fitSessionTarget(*c)
+	if err != nil {
+		return
+	}
+	c.MesoCount, c.lastMesoAdjust = n, adjust
+}
+
+// mesoTargetDuration 返回中循环的目标总时间，大循环的最后一个中循环计入目标会话时长的调整
+func mesoTargetDuration(c *Config, isLastMeso bool) time.Duration {
+	if isLastMeso {
+		return c.mesoDuration() + c.lastMesoAdjust
+	}
+	return c.mesoDuration()
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// configPath 是配置文件路径，默认为 config.json，可以用 -config 指定
+var configPath = "config.json"
+
+var errSetupInputClosed = errors.New("输入已结束，配置未完成")
+
+// setupField 是首次配置时询问的一项，默认值与仓库自带的 config.json 一致
+type setupField struct {
+	Key      string // config.json 中的字段名
+	Prompt   string
+	Default  int
+	Min, Max int
+	ptr      func(c *Config) *int
+}
+
+var setupFields = []setupField{
+	{"小循环基础时间秒", "每个小循环的基础时长（秒）", 120, 1, 86400, func(c *Config) *int { return &c.MicroBaseS }},
+	{"小循环随机偏移秒", "小循环时长的随机偏移（秒）", 30, 0, 86400, func(c *Config) *int { return &c.MicroOffsetS }},
+	{"小循环休息时间秒", "小循环之间的休息（秒）", 10, 0, 86400, func(c *Config) *int { return &c.MicroRestS }},
+	{"中循环总时间分", "每个中循环的总时长（分）", 25, 1, 1440, func(c *Config) *int { return &c.MesoDurationM }},
+	{"中循环休息时间分", "中循环之间的休息（分）", 5, 0, 1440, func(c *Config) *int { return &c.MesoRestM }},
+	{"中循环组数", "每个大循环包含几个中循环", 3, 1, maxCycleCount, func(c *Config) *int { return &c.MesoCount }},
+	{"大循环休息时间分", "大循环之间的休息（分）", 30, 0, 1440, func(c *Config) *int { return &c.MacroRestM }},
+	{"大循环次数", "一共进行几个大循环（0 表示一直循环）", 0, 0, maxCycleCount, func(c *Config) *int { return &c.MacroCount }},
+	{"端口", "Web 界面端口", defaultPort, 1, 65535, func(c *Config) *int { return &c.Port }},
+}
+
+// parse 解析一项输入，空输入使用默认值
+func (f setupField) parse(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return f.Default, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%s 需要填写整数", f.Key)
+	}
+	if v < f.Min || v > f.Max {
+		return 0, fmt.Errorf("%s 应在 %d-%d 之间", f.Key, f.Min, f.Max)
+	}
+	return v, nil
+}
+
+func configMissing() bool {
+	_, err := os.Stat(configPath)
+	return errors.Is(err, os.ErrNotExist)
+}
+
+// stdinIsTerminal 判断是否可以在终端中交互；隐形版没有控制台，标准输入不可用
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// runSetup 引导首次配置：有终端时在终端中逐项询问，Web 版没有终端时打开配置页面。
+// 完成后写入 config.json，随后正常加载
+func runSetup() error {
+	var c Config
+	var err error
+	if stdinIsTerminal() || !webSetupAvailable() {
+		c, err = promptSetup(os.Stdin, os.Stdout)
+		// 标准输入不可交互（如重定向自空设备）时改用配置页面
+		if errors.Is(err, errSetupInputClosed) && webSetupAvailable() {
+			c, err = runWebSetup()
+		}
+	} else {
+		c, err = runWebSetup()
+	}
+	if err != nil {
+		return err
+	}
+	if err := writeSetupConfig(c); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", configPath, err)
+	}
+	progress("setup_done", 0, "配置已保存到 %s", configPath)
+	return nil
+}
+
+// promptSetup 在终端中逐项询问，输入无效时重新询问该项
+func promptSetup(in io.Reader, out io.Writer) (Config, error) {
+	fmt.Fprintln(out, "首次使用，开始配置番茄钟（直接回车使用方括号中的默认值）:")
+	scanner := bufio.NewScanner(in)
+	for {
+		var c Config
+		for _, f := range setupFields {
+			for {
+				fmt.Fprintf(out, "  %s [%d]: ", f.Prompt, f.Default)
+				if !scanner.Scan() {
+					if err := scanner.Err(); err != nil {
+						return c, err
+					}
+					return c, errSetupInputClosed
+				}
+				v, err := f.parse(scanner.Text())
+				if err != nil {
+					fmt.Fprintf(out, "  %v\n", err)
+					continue
+				}
+				*f.ptr(&c) = v
+				break
+			}
+		}
+
+		checked := c
+		applyDefaults(&checked)
+		if err := validateConfig(checked); err != nil {
+			fmt.Fprintf(out, "配置无效: %v，请重新填写。\n", err)
+			continue
+		}
+		return c, nil
+	}
+}
+
+// setupFromValues 用表单提交的值生成配置，供 Web 配置页面使用
+func setupFromValues(get func(key string) string) (Config, error) {
+	var c Config
+	for _, f := range setupFields {
+		v, err := f.parse(get(f.Key))
+		if err != nil {
+			return c, err
+		}
+		*f.ptr(&c) = v
+	}
+	checked := c
+	applyDefaults(&checked)
+	if err := validateConfig(checked); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// writeSetupConfig 只写入询问过的字段，按询问顺序排列，其余保持默认。
+// 已有配置文件（-setup）时先备份为 config.json.bak
+func writeSetupConfig(c Config) error {
+	if !configMissing() {
+		if err := os.Rename(configPath, configPath+".bak"); err != nil {
+			return err
+		}
+		progress("setup_backup", 0, "原配置已备份为 %s.bak", configPath)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("{\n")
+	for i, f := range setupFields {
+		key, _ := json.Marshal(f.Key)
+		fmt.Fprintf(&b, "    %s: %d", key, *f.ptr(&c))
+		if i < len(setupFields)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n")
+	return writeFileAtomic(configPath, b.Bytes())
+}
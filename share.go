@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// 分享码解压后的长度上限，防止恶意构造的分享码占用大量内存
+const maxShareJSON = 64 << 10
+
+// sharedSettings 取出可以分享的时间安排（与监视配置文件时可以生效的字段相同，外加按星期覆盖），
+// 已叠加当前预设，不包含端口、Webhook、MQTT 等与个人环境或密钥有关的设置
+func sharedSettings(c Config) Config {
+	var s Config
+	copyReloadable(&s, withPreset(c))
+	s.Weekdays = c.Weekdays
+	return s
+}
+
+// sharedFields 返回可以分享的字段，去掉与零值相同的字段以缩短分享码
+func sharedFields(c Config) (map[string]json.RawMessage, error) {
+	fields, err := configFields(sharedSettings(c))
+	if err != nil {
+		return nil, err
+	}
+	zero, err := configFields(Config{})
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range fields {
+		if bytes.Equal(v, zero[k]) || string(v) == "{}" {
+			delete(fields, k)
+		}
+	}
+	return fields, nil
+}
+
+// configFields 把配置按 JSON 键拆开
+func configFields(c Config) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(data, &fields)
+	return fields, err
+}
+
+// encodeShareCode 把当前时间安排编码成紧凑、可放进 URL 的分享码：
+// 去掉零值字段的 JSON，deflate 压缩后用 URL 安全的 base64 编码
+func encodeShareCode(c Config) (string, error) {
+	fields, err := sharedFields(c)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// decodeShareCode 解码分享码，把其中的时间安排套用到 base 上并校验，返回完整的新配置
+func decodeShareCode(code string, base Config) (Config, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(code)
+	if err != nil {
+		return Config{}, fmt.Errorf("分享码格式错误")
+	}
+	data, err := io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(raw)), maxShareJSON+1))
+	if err != nil {
+		return Config{}, fmt.Errorf("分享码无法解压: %v", err)
+	}
+	if len(data) > maxShareJSON {
+		return Config{}, fmt.Errorf("分享码过长")
+	}
+
+	var shared Config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&shared); err != nil {
+		return Config{}, fmt.Errorf("分享码内容无效: %v", err)
+	}
+	// 只接受可以分享的字段，其他字段（如端口、Webhook）即使写进分享码也不采用
+	shared = sharedSettings(shared)
+
+	c := base
+	copyReloadable(&c, shared)
+	c.Weekdays = shared.Weekdays
+	// 分享的时间安排已叠加分享者的预设，导入后不再叠加本地的预设
+	c.ActivePreset = ""
+	applyDefaults(&c)
+	if err := validateConfig(c); err != nil {
+		return Config{}, err
+	}
+	if err := validateWeekdays(c); err != nil {
+		return Config{}, err
+	}
+	if err := validatePresets(c); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gopxl/beep/v2/speaker"
+)
+
+var (
+	// appCtx 是所有计时器循环 context 的父 context，收到退出信号时取消
+	appCtx, stopApp = context.WithCancel(context.Background())
+
+	// shuttingDown 在收到退出信号后为 1，之后不再播放提示音
+	shuttingDown int32
+)
+
+// handleSignals 在收到 Ctrl+C（SIGINT）或 SIGTERM 时正常退出；再次收到信号时立即退出
+func handleSignals() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-ch
+		go func() {
+			<-ch
+			log.Println("再次收到退出信号，立即退出")
+			os.Exit(1)
+		}()
+		shutdown(sig)
+	}()
+}
+
+func isShuttingDown() bool {
+	return atomic.LoadInt32(&shuttingDown) == 1
+}
+
+// shutdown 停止计时器循环、关闭 Web 服务器和音频设备，等待进行中的历史记录写完后结束进程
+func shutdown(sig os.Signal) {
+	atomic.StoreInt32(&shuttingDown, 1)
+	progress("shutdown", 0, ">>> 收到 %v，正在退出…", sig)
+
+	// 计时器循环在等待中观察到取消后立即退出
+	stopApp()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := stopWebServer(ctx); err != nil {
+		log.Printf("关闭 Web 服务器失败: %v", err)
+	}
+
+	// 等待正在写入的历史记录完成
+	historyMu.Lock()
+	historyMu.Unlock()
+
+	speakerInitMu.Lock()
+	if atomic.LoadInt32(&speakerInited) == 1 {
+		speaker.Close()
+		atomic.StoreInt32(&speakerInited, 0)
+	}
+	speakerInitMu.Unlock()
+
+	log.Println("番茄钟已退出")
+	closeTimerDone()
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// simulation 按顺序记录模拟出的每个阶段，累计专注和休息时长
+type simulation struct {
+	w       io.Writer
+	start   time.Time
+	elapsed time.Duration
+	focus   time.Duration
+	rest    time.Duration
+}
+
+// phase 输出一个阶段的开始时刻、累计时长、阶段时长和说明，然后把时钟向后推
+func (s *simulation) phase(d time.Duration, focus bool, format string, args ...interface{}) {
+	fmt.Fprintf(s.w, "%s  %s  %-8v %s\n", s.start.Add(s.elapsed).Format("15:04:05"), clockDuration(s.elapsed), d, fmt.Sprintf(format, args...))
+	s.elapsed += d
+	if focus {
+		s.focus += d
+	} else {
+		s.rest += d
+	}
+}
+
+// clockDuration 把时长格式化为 H:MM:SS
+func clockDuration(d time.Duration) string {
+	sec := int(d.Seconds())
+	return fmt.Sprintf("%d:%02d:%02d", sec/3600, sec/60%60, sec%60)
+}
+
+// simulateSession 按当前配置规划整个会话并输出每个阶段，不等待也不播放提示音。
+// 小循环时长使用 scheduleRand，配置了 随机种子 时每次输出相同；
+// 跳过、暂停、自适应中循环休息和等待确认取决于运行中的操作，这里按没有发生计算
+func simulateSession(w io.Writer, start time.Time) {
+	cfg := currentConfig()
+	macros := cfg.MacroCount
+	if macros <= 0 {
+		macros = 1
+		fmt.Fprintf(w, "大循环次数为 0（无限循环），只模拟一个大循环\n")
+	}
+	fmt.Fprintf(w, "模拟时间安排（从 %s 开始）\n\n", start.Format("2006-01-02 15:04:05"))
+
+	s := &simulation{w: w, start: start}
+	for m := 1; m <= macros; m++ {
+		fmt.Fprintf(w, ">>> 大循环 %d/%d\n", m, macros)
+		for i := 1; i <= cfg.MesoCount; i++ {
+			isLastMeso := i == cfg.MesoCount
+			target := mesoTargetDuration(cfg, isLastMeso)
+			// 与计时时相同，规划为空时运行一个基础时长的小循环
+			micros, _ := withFallbackMicro(cfg, planMesoSchedule(cfg, target, scheduleRand.Intn))
+			fmt.Fprintf(w, "  >> 中循环 %d/%d: %d 个小循环，总时长 %v（目标 %v）\n",
+				i, cfg.MesoCount, len(micros), mesoTotalDuration(cfg, micros), target)
+			for j, d := range micros {
+				s.phase(d, true, "    小循环 %d/%d 专注", j+1, len(micros))
+				if restAfterMicro(cfg, j, len(micros)) {
+					s.phase(cfg.microRest(), false, "    小循环休息")
+				}
+			}
+			if !isLastMeso {
+				s.phase(cfg.mesoRest(), false, "  中循环休息")
+			} else if pause := time.Duration(cfg.FinalMesoPauseS) * time.Second; pause > 0 {
+				s.phase(pause, false, "  大循环过渡")
+			}
+		}
+		if m == cfg.MacroCount {
+			break
+		}
+		s.phase(cfg.macroRest(), false, "大循环休息")
+		if gap := cfg.macroGap(); gap > 0 {
+			s.phase(gap, false, "大循环间隔")
+		}
+		if cfg.MacroGapAck {
+			fmt.Fprintf(w, "%s  %s  %-8s %s\n", start.Add(s.elapsed).Format("15:04:05"), clockDuration(s.elapsed), "-", "等待确认后开始下一个大循环")
+		}
+	}
+
+	fmt.Fprintf(w, "\n合计: 专注 %v，休息 %v，总时长 %v，结束于 %s\n",
+		s.focus, s.rest, s.elapsed, start.Add(s.elapsed).Format("2006-01-02 15:04:05"))
+}
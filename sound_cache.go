@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+)
+
+var (
+	// soundCacheMu 保护 soundCache：路径到解码并重采样后的提示音
+	soundCacheMu sync.Mutex
+	soundCache   = map[string]*beep.Buffer{}
+)
+
+// preloadSounds 把当前会用到的提示音解码到内存，之后播放时不再读取和解码文件；
+// 已经缓存的文件跳过，失败的文件在播放时仍按原来的方式读取
+func preloadSounds() {
+	loaded := 0
+	for _, path := range soundFiles() {
+		if cachedSound(path) != nil {
+			continue
+		}
+		buf, err := decodeToBuffer(path)
+		if err != nil {
+			log.Printf("预加载提示音失败，播放时再读取: %v", err)
+			continue
+		}
+		soundCacheMu.Lock()
+		soundCache[path] = buf
+		soundCacheMu.Unlock()
+		loaded++
+	}
+	if loaded > 0 {
+		log.Printf("已预加载 %d 个提示音", loaded)
+	}
+}
+
+// decodeToBuffer 解码整个文件并重采样到播放用的采样率
+func decodeToBuffer(path string) (*beep.Buffer, error) {
+	streamer, format, err := decodeSound(path)
+	if err != nil {
+		return nil, err
+	}
+	defer streamer.Close()
+
+	var s beep.Streamer = streamer
+	if format.SampleRate != sampleRate {
+		s = beep.Resample(4, format.SampleRate, sampleRate, streamer)
+		format.SampleRate = sampleRate
+	}
+	buf := beep.NewBuffer(format)
+	buf.Append(s)
+	return buf, nil
+}
+
+// reloadSounds 重新解码当前会用到的全部提示音，用于替换了 Sounds 目录中的文件之后。
+// 解码失败或解码后没有音频数据的文件保留原来的缓存，返回成功重新加载的文件和失败原因
+func reloadSounds() (reloaded []string, failed map[string]string) {
+	failed = map[string]string{}
+	for _, path := range soundFiles() {
+		buf, err := decodeToBuffer(path)
+		if err == nil && buf.Len() == 0 {
+			err = errors.New("解码后没有音频数据")
+		}
+		if err != nil {
+			log.Printf("重新加载提示音失败，继续使用原来的: %v", err)
+			failed[path] = err.Error()
+			continue
+		}
+		soundCacheMu.Lock()
+		soundCache[path] = buf
+		soundCacheMu.Unlock()
+		reloaded = append(reloaded, path)
+	}
+	if len(reloaded) > 0 {
+		log.Printf("已重新加载 %d 个提示音: %s", len(reloaded), strings.Join(reloaded, ", "))
+	}
+	return reloaded, failed
+}
+
+// soundFilesChanged 比较提示音文件的修改时间和上次记录的 mods，有变化时更新 mods 并返回 true；
+// 第一次调用只记录，不算变化
+func soundFilesChanged(mods map[string]time.Time) bool {
+	first := len(mods) == 0
+	changed := false
+	for _, path := range soundFiles() {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if prev, ok := mods[path]; !ok || !prev.Equal(info.ModTime()) {
+			mods[path] = info.ModTime()
+			changed = true
+		}
+	}
+	return changed && !first
+}
+
+// cachedSound 返回已预加载的提示音，没有时返回 nil
+func cachedSound(path string) *beep.Buffer {
+	soundCacheMu.Lock()
+	defer soundCacheMu.Unlock()
+	return soundCache[path]
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// activeThemeName 保存运行时切换后的提示音主题，未切换过时使用配置中的主题
+var activeThemeName atomic.Value
+
+// activeSoundTheme 返回当前使用的提示音主题，"" 表示默认的 Sounds 平铺布局
+func activeSoundTheme() string {
+	if v, ok := activeThemeName.Load().(string); ok {
+		return v
+	}
+	return currentConfig().SoundTheme
+}
+
+// themeSoundPath 返回主题目录中事件对应的文件，文件名就是事件名
+func themeSoundPath(dir, theme, event string) string {
+	return filepath.Join(dir, theme, event+".mp3")
+}
+
+// checkSoundTheme 确认主题目录存在且包含全部事件的提示音。
+// session_end 可以省略，缺少时使用结束提示音配置；启动和中循环过半提示音不属于主题
+func checkSoundTheme(dir, theme string) error {
+	if theme == "" || theme != filepath.Base(theme) || strings.HasPrefix(theme, ".") {
+		return fmt.Errorf("无效的提示音主题名 %q", theme)
+	}
+	if info, err := os.Stat(filepath.Join(dir, theme)); err != nil || !info.IsDir() {
+		return fmt.Errorf("提示音主题 %q 不存在于 %s", theme, dir)
+	}
+
+	var missing []string
+	for _, ev := range allEvents {
+		if ev == eventSessionEnd || ev == eventStartup || ev == eventMesoHalf || ev == eventCooldownStart || ev == eventCooldownEnd {
+			continue
+		}
+		if _, err := os.Stat(themeSoundPath(dir, theme, ev)); err != nil {
+			missing = append(missing, ev+".mp3")
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("提示音主题 %q 缺少文件: %s", theme, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// setSoundTheme 在运行时切换提示音主题，"" 切回默认布局
+func setSoundTheme(theme string) error {
+	if theme != "" {
+		if err := checkSoundTheme(currentConfig().SoundThemesDir, theme); err != nil {
+			return err
+		}
+	}
+	activeThemeName.Store(theme)
+	log.Printf("提示音主题已切换为 %q", theme)
+	// 在后台预加载新主题的提示音
+	go preloadSounds()
+	return nil
+}
+
+// listSoundThemes 列出主题目录下的全部子目录，不检查文件是否齐全
+func listSoundThemes() ([]string, error) {
+	entries, err := os.ReadDir(currentConfig().SoundThemesDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var themes []string
+	for _, e := range entries {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			themes = append(themes, e.Name())
+		}
+	}
+	sort.Strings(themes)
+	return themes, nil
+}
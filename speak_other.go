@@ -0,0 +1,22 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"runtime"
+)
+
+// speak 使用系统自带的朗读命令：macOS 为 say，其他系统尝试 espeak
+func speak(msg string) error {
+	name := "espeak"
+	if runtime.GOOS == "darwin" {
+		name = "say"
+	}
+	if _, err := exec.LookPath(name); err != nil {
+		return errors.New("未找到语音播报命令 " + name)
+	}
+	return exec.Command(name, msg).Run()
+}
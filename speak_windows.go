@@ -0,0 +1,7 @@
+package main
+
+// speak 通过 PowerShell 调用系统自带的 System.Speech 朗读文本
+func speak(msg string) error {
+	const script = `Add-Type -AssemblyName System.Speech; (New-Object System.Speech.Synthesis.SpeechSynthesizer).Speak($env:FANQIE_TTS_TEXT)`
+	return runPowerShell(script, "FANQIE_TTS_TEXT="+msg)
+}
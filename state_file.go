@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// startStateFileWriterIfNeeded 在配置了状态文件或目录时启动写出协程
+func startStateFileWriterIfNeeded() {
+	cfg := currentConfig()
+	if cfg.StateFile == "" && cfg.StateDir == "" {
+		return
+	}
+	go runStateFileWriter()
+}
+
+func runStateFileWriter() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("状态文件写出崩溃: %v", r)
+		}
+	}()
+
+	if dir := currentConfig().StateDir; dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Printf("创建状态文件目录失败 %s: %v", dir, err)
+			return
+		}
+	}
+	log.Println("状态文件写出已启动")
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		writeStateFiles(readStatus())
+	}
+}
+
+// stateFields 返回写入状态文件的字段，顺序固定便于挂件解析
+func stateFields(s Status) [][2]string {
+	fields := [][2]string{
+		{"phase", s.Phase},
+		{"remaining", formatTime(s.CurrentRemaining())},
+		{"remaining_seconds", fmt.Sprintf("%d", int(s.CurrentRemaining()))},
+		{"total_seconds", fmt.Sprintf("%d", int(s.CurrentTotal))},
+	}
+	if s.InMeso {
+		fields = append(fields,
+			[2]string{"meso_remaining", formatTime(s.MesoRemaining())},
+			[2]string{"meso_remaining_seconds", fmt.Sprintf("%d", int(s.MesoRemaining()))},
+		)
+	} else {
+		fields = append(fields,
+			[2]string{"meso_remaining", ""},
+			[2]string{"meso_remaining_seconds", ""},
+		)
+	}
+	return fields
+}
+
+func writeStateFiles(s Status) {
+	cfg := currentConfig()
+	fields := stateFields(s)
+
+	// 单文件: 每行一个 key=value
+	if cfg.StateFile != "" {
+		var b strings.Builder
+		for _, f := range fields {
+			fmt.Fprintf(&b, "%s=%s\n", f[0], f[1])
+		}
+		if err := writeFileAtomic(cfg.StateFile, []byte(b.String())); err != nil {
+			log.Printf("写入状态文件失败 %s: %v", cfg.StateFile, err)
+		}
+	}
+
+	// 目录: 每个字段一个文件，内容只有值本身
+	if cfg.StateDir != "" {
+		for _, f := range fields {
+			path := filepath.Join(cfg.StateDir, f[0]+".txt")
+			if err := writeFileAtomic(path, []byte(f[1])); err != nil {
+				log.Printf("写入状态文件失败 %s: %v", path, err)
+			}
+		}
+	}
+}
+
+// writeFileAtomic 先写临时文件再重命名，避免读取方读到写了一半的内容
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Status 是计时器在某一时刻的状态快照，时间单位为秒
+type Status struct {
+	At             time.Time // 读取快照的时间
+	Phase          string
+	CurrentTotal   float64
+	CurrentElapsed float64
+	InMeso         bool
+	MesoTotal      float64
+	MesoElapsed    float64
+	Streak         int64 // 本次大循环内连续完成（未跳过）的小循环数
+	AwaitingAck    bool  // 当前阶段等待用户确认
+	Paused         bool  // 计时已暂停
+	ActiveSkips    int64 // 因仍在输入而跳过的小休息次数
+	MesoEndedEarly int64 // 提前结束的中循环数
+	SkipsRemaining int   // 本中循环还能跳过的次数，-1 表示不限制
+
+	// 今天剩余的专注预算秒数，没有配置 每日专注预算分 时 HasDailyBudget 为 false
+	DailyBudgetRemaining float64
+	HasDailyBudget       bool
+
+	// 按当前配置估算的一个大循环和整个会话的计划时长，无限循环时 HasPlannedSession 为 false
+	PlannedMacroSeconds   float64
+	PlannedSessionSeconds float64
+	HasPlannedSession     bool
+
+	MakeupCount   int64   // 补回小循环的次数
+	MakeupSeconds float64 // 补回小循环累计秒数
+
+	QuickFocusCount   int64   // 已完成的快速专注次数
+	QuickFocusSeconds float64 // 快速专注累计秒数
+
+	TotalFocusSeconds float64 // 本次运行已完成的专注阶段（小循环专注和快速专注）累计秒数
+
+	LastCycle cycleTiming // 最近一个结束的阶段的计划与实际时长
+}
+
+var (
+	// timingSeq 是计时状态的版本号（seqlock）：写入期间为奇数，写完后为偶数。
+	// 读取方在版本号不变时才采用读到的值，保证阶段、起点和时长属于同一次写入，
+	// 不会在阶段切换的瞬间读到新的起点配旧的时长
+	timingSeq uint64
+	// timingMu 只在写入方之间互斥，看门狗重启时新旧计时器循环可能短暂重叠
+	timingMu sync.Mutex
+)
+
+// updateTiming 在一次版本号递增中修改计时状态，读取方不会看到修改了一半的值
+func updateTiming(f func()) {
+	timingMu.Lock()
+	defer timingMu.Unlock()
+	atomic.AddUint64(&timingSeq, 1)
+	f()
+	atomic.AddUint64(&timingSeq, 1)
+}
+
+// timingSnapshot 是一组一致的计时状态
+type timingSnapshot struct {
+	phase        string
+	cStart, cDur int64
+	mStart, mDur int64
+	inMeso       bool
+	paused       bool
+	pausedAt     int64
+}
+
+// readTiming 无锁读取一组一致的计时状态，遇到正在进行的写入时重读
+func readTiming() timingSnapshot {
+	for {
+		seq := atomic.LoadUint64(&timingSeq)
+		if seq%2 == 1 {
+			runtime.Gosched()
+			continue
+		}
+		t := timingSnapshot{
+			phase:    getPhase(),
+			cStart:   atomic.LoadInt64(&currentStartNano),
+			cDur:     atomic.LoadInt64(&currentDuration),
+			mStart:   atomic.LoadInt64(&mesoStartNano),
+			mDur:     atomic.LoadInt64(&mesoDuration),
+			inMeso:   atomic.LoadInt32(&inMeso) == 1,
+			paused:   atomic.LoadInt32(&paused) == 1,
+			pausedAt: atomic.LoadInt64(&pausedAtNano),
+		}
+		if atomic.LoadUint64(&timingSeq) == seq {
+			return t
+		}
+	}
+}
+
+// readStatus 无锁读取一组一致的计时状态并计算当前进度，已用时间不会超过总时长
+func readStatus() Status {
+	t := readTiming()
+	at := time.Now()
+	now := at.UnixNano()
+	// 暂停期间进度停在暂停的时刻
+	if t.paused {
+		now = t.pausedAt
+	}
+
+	cStart, cDur := t.cStart, t.cDur
+	mStart, mDur := t.mStart, t.mDur
+	inMesoFlag := t.inMeso
+
+	currentElapsed := float64(now-cStart) / 1e9
+	cTotalSec := float64(cDur) / 1e9
+	if currentElapsed > cTotalSec {
+		currentElapsed = cTotalSec
+	}
+
+	mesoElapsed := float64(now-mStart) / 1e9
+	mTotalSec := float64(mDur) / 1e9
+	if mesoElapsed > mTotalSec {
+		mesoElapsed = mTotalSec
+	}
+
+	budget, hasBudget := dailyBudgetRemaining(at)
+	plannedMacro, plannedSession, hasSession := plannedDurations()
+
+	return Status{
+		At:             at,
+		Phase:          t.phase,
+		CurrentTotal:   cTotalSec,
+		CurrentElapsed: currentElapsed,
+		InMeso:         inMesoFlag,
+		MesoTotal:      mTotalSec,
+		MesoElapsed:    mesoElapsed,
+		Streak:         getMicroStreak(),
+		AwaitingAck:    isAwaitingAck(),
+		Paused:         t.paused,
+		ActiveSkips:    atomic.LoadInt64(&activeRestSkips),
+		MesoEndedEarly: atomic.LoadInt64(&mesoEndedEarly),
+		SkipsRemaining: skipsRemaining(),
+
+		DailyBudgetRemaining: budget.Seconds(),
+		HasDailyBudget:       hasBudget,
+
+		PlannedMacroSeconds:   plannedMacro.Seconds(),
+		PlannedSessionSeconds: plannedSession.Seconds(),
+		HasPlannedSession:     hasSession,
+
+		MakeupCount:   atomic.LoadInt64(&makeupCount),
+		MakeupSeconds: float64(atomic.LoadInt64(&makeupNano)) / 1e9,
+
+		QuickFocusCount:   atomic.LoadInt64(&quickFocusCount),
+		QuickFocusSeconds: float64(atomic.LoadInt64(&quickFocusNano)) / 1e9,
+
+		TotalFocusSeconds: float64(atomic.LoadInt64(&totalFocusNano)) / 1e9,
+
+		LastCycle: getLastCycleTiming(),
+	}
+}
+
+// wholeSeconds 把时间取整到秒：已用时间向下取整，总时长四舍五入，
+// 这样剩余时间只在整秒处变化，不会因为轮询时刻不同而来回跳动
+func (s Status) wholeSeconds() Status {
+	s.CurrentTotal = math.Round(s.CurrentTotal)
+	s.CurrentElapsed = math.Floor(s.CurrentElapsed)
+	s.MesoTotal = math.Round(s.MesoTotal)
+	s.MesoElapsed = math.Floor(s.MesoElapsed)
+	s.QuickFocusSeconds = math.Floor(s.QuickFocusSeconds)
+	s.MakeupSeconds = math.Floor(s.MakeupSeconds)
+	s.TotalFocusSeconds = math.Floor(s.TotalFocusSeconds)
+	s.DailyBudgetRemaining = math.Floor(s.DailyBudgetRemaining)
+	return s
+}
+
+// CurrentRemaining 返回当前阶段剩余秒数
+func (s Status) CurrentRemaining() float64 {
+	return nonNegative(s.CurrentTotal - s.CurrentElapsed)
+}
+
+// MesoRemaining 返回当前中循环剩余秒数
+func (s Status) MesoRemaining() float64 {
+	return nonNegative(s.MesoTotal - s.MesoElapsed)
+}
+
+func nonNegative(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	return v
+}
+
+// formatTime 把秒数格式化为 MM:SS；开启 显示小时 时，一小时及以上显示为 HH:MM:SS
+func formatTime(seconds float64) string {
+	sec := int(seconds)
+	if currentConfig().ShowHours && sec >= 3600 {
+		return fmt.Sprintf("%02d:%02d:%02d", sec/3600, sec%3600/60, sec%60)
+	}
+	m := sec / 60
+	s := sec % 60
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
+
+// formatHourMinute 把较长的时长格式化为 "1h22m" 或 "45m"，不足一分钟的部分舍去
+func formatHourMinute(d time.Duration) string {
+	m := int(d / time.Minute)
+	if m >= 60 {
+		return fmt.Sprintf("%dh%02dm", m/60, m%60)
+	}
+	return fmt.Sprintf("%dm", m)
+}
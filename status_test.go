@@ -0,0 +1,114 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestReadTimingConsistent 在多个协程不断切换阶段和中循环时反复读取，
+// 每次读到的阶段、起点和时长都必须属于同一次写入
+func TestReadTimingConsistent(t *testing.T) {
+	useTestConfig(t, testSchedule())
+	reset := func() {
+		setCurrentTask(phaseIdle, 0)
+		updateTiming(func() {
+			atomic.StoreInt64(&mesoStartNano, 0)
+			atomic.StoreInt64(&mesoDuration, 0)
+			atomic.StoreInt32(&inMeso, 0)
+		})
+	}
+	// 之前的测试可能留下了专注阶段，写入方开始前读到它会误判为不一致
+	reset()
+	t.Cleanup(reset)
+
+	focusStart := time.Unix(1000, 0)
+	restStart := time.Unix(2000, 0)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+
+	// 两个写入方交替切换阶段，模拟看门狗重启时新旧循环短暂重叠
+	for w := 0; w < 2; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if i%2 == 0 {
+					setCurrentTaskAt(phaseMicroFocus, time.Minute, focusStart)
+				} else {
+					setCurrentTaskAt(phaseMicroRest, 10*time.Second, restStart)
+				}
+			}
+		}()
+	}
+	// 中循环的起点和时长总是成对写入：时长是起点的两倍
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for k := int64(1); ; k++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			updateTiming(func() {
+				atomic.StoreInt64(&mesoStartNano, k)
+				atomic.StoreInt64(&mesoDuration, 2*k)
+				atomic.StoreInt32(&inMeso, 1)
+			})
+		}
+	}()
+
+	deadline := time.Now().Add(200 * time.Millisecond)
+	reads := 0
+	for time.Now().Before(deadline) {
+		s := readTiming()
+		reads++
+		switch s.phase {
+		case phaseMicroFocus:
+			if s.cStart != focusStart.UnixNano() || s.cDur != int64(time.Minute) {
+				t.Fatalf("专注阶段读到了不一致的起点 %d 和时长 %d", s.cStart, s.cDur)
+			}
+		case phaseMicroRest:
+			if s.cStart != restStart.UnixNano() || s.cDur != int64(10*time.Second) {
+				t.Fatalf("休息阶段读到了不一致的起点 %d 和时长 %d", s.cStart, s.cDur)
+			}
+		}
+		if s.inMeso && s.mDur != 2*s.mStart {
+			t.Fatalf("中循环读到了不一致的起点 %d 和时长 %d", s.mStart, s.mDur)
+		}
+	}
+	close(stop)
+	wg.Wait()
+	if reads == 0 {
+		t.Fatal("没有完成任何一次读取")
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	for _, tc := range []struct {
+		seconds   float64
+		showHours bool
+		want      string
+	}{
+		{3599, false, "59:59"},
+		{3600, false, "60:00"},
+		{3661, false, "61:01"},
+		{3599, true, "59:59"},
+		{3600, true, "01:00:00"},
+		{3661, true, "01:01:01"},
+	} {
+		c := testSchedule()
+		c.ShowHours = tc.showHours
+		useTestConfig(t, c)
+		if got := formatTime(tc.seconds); got != tc.want {
+			t.Errorf("formatTime(%v) 显示小时=%v 得到 %q，应为 %q", tc.seconds, tc.showHours, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 主题模式
+const (
+	themeDark  = "dark"
+	themeLight = "light"
+	themeAuto  = "auto"
+)
+
+// ThemeConfig 是窗口配色的配置
+type ThemeConfig struct {
+	Mode string `json:"模式"` // "dark"（默认）、"light" 或 "auto"（按时间切换）
+
+	// auto 模式下，[浅色开始小时, 浅色结束小时) 之间使用浅色主题
+	LightFrom  int `json:"浅色开始小时"`
+	LightUntil int `json:"浅色结束小时"`
+
+	Dark  PaletteConfig `json:"深色"`
+	Light PaletteConfig `json:"浅色"`
+}
+
+// PaletteConfig 是一套配色，取值为 "#RRGGBB" 或 "#RRGGBBAA"，留空使用默认颜色
+type PaletteConfig struct {
+	Background    string `json:"背景"`
+	Text          string `json:"文字"`
+	BarBackground string `json:"进度条底色"`
+	CurrentBar    string `json:"当前进度条"`
+	RestBar       string `json:"休息进度条"` // 休息阶段的当前进度条，留空时与 当前进度条 相同
+	MesoBar       string `json:"中循环进度条"`
+}
+
+// palette 是解析后的配色
+type palette struct {
+	background    color.RGBA
+	text          color.RGBA
+	barBackground color.RGBA
+	currentBar    color.RGBA
+	restBar       color.RGBA
+	mesoBar       color.RGBA
+}
+
+var (
+	defaultDarkPalette = palette{
+		background:    color.RGBA{0, 0, 0, 255},
+		text:          color.RGBA{255, 255, 255, 255},
+		barBackground: color.RGBA{51, 51, 51, 255},
+		currentBar:    color.RGBA{76, 175, 80, 255},
+		mesoBar:       color.RGBA{33, 150, 243, 255},
+	}
+	defaultLightPalette = palette{
+		background:    color.RGBA{245, 245, 245, 255},
+		text:          color.RGBA{33, 33, 33, 255},
+		barBackground: color.RGBA{208, 208, 208, 255},
+		currentBar:    color.RGBA{56, 142, 60, 255},
+		mesoBar:       color.RGBA{25, 118, 210, 255},
+	}
+)
+
+var (
+	// 第一次使用时根据配置解析的深色、浅色配色，窗口和 /status 共用
+	paletteOnce  sync.Once
+	darkPalette  palette
+	lightPalette palette
+)
+
+// currentPalette 根据主题模式和当前时间选择配色
+func currentPalette() palette {
+	cfg := currentConfig()
+	paletteOnce.Do(func() {
+		darkPalette = cfg.Theme.Dark.resolve(defaultDarkPalette)
+		lightPalette = cfg.Theme.Light.resolve(defaultLightPalette)
+	})
+	if cfg.Theme.useLightTheme(time.Now()) {
+		return lightPalette
+	}
+	return darkPalette
+}
+
+// colorHints 以 "#RRGGBB" 返回当前配色，供 Web 页面与窗口使用相同的颜色
+func (p palette) colorHints() map[string]string {
+	return map[string]string{
+		"background":     hexColor(p.background),
+		"text":           hexColor(p.text),
+		"bar_background": hexColor(p.barBackground),
+		"current_bar":    hexColor(p.currentBar),
+		"rest_bar":       hexColor(p.restBar),
+		"meso_bar":       hexColor(p.mesoBar),
+	}
+}
+
+// hexColor 把颜色格式化为 "#RRGGBB"，不透明度不是 255 时为 "#RRGGBBAA"
+func hexColor(c color.RGBA) string {
+	if c.A == 255 {
+		return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	}
+	return fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+}
+
+// isRestPhase 判断阶段是否为休息，休息时当前进度条使用 休息进度条 的颜色
+func isRestPhase(phase string) bool {
+	switch phase {
+	case phaseMicroRest, phaseMesoRest, phaseMacroRest, phaseMacroGap, phaseCooldown:
+		return true
+	}
+	return false
+}
+
+func isValidThemeMode(mode string) bool {
+	switch mode {
+	case "", themeDark, themeLight, themeAuto:
+		return true
+	}
+	return false
+}
+
+// parseHexColor 解析 "#RRGGBB" 或 "#RRGGBBAA"
+func parseHexColor(s string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) != 6 && len(hex) != 8 {
+		return color.RGBA{}, fmt.Errorf("颜色格式应为 #RRGGBB 或 #RRGGBBAA: %q", s)
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("无效的颜色 %q", s)
+	}
+	return color.RGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}, nil
+}
+
+// resolve 把配置中的颜色覆盖到默认配色上，无法解析的颜色保留默认值；
+// 没有配置休息进度条时与当前进度条相同
+func (p PaletteConfig) resolve(def palette) palette {
+	out := def
+	out.restBar = color.RGBA{}
+	for _, f := range []struct {
+		value string
+		dst   *color.RGBA
+	}{
+		{p.Background, &out.background},
+		{p.Text, &out.text},
+		{p.BarBackground, &out.barBackground},
+		{p.CurrentBar, &out.currentBar},
+		{p.RestBar, &out.restBar},
+		{p.MesoBar, &out.mesoBar},
+	} {
+		if f.value == "" {
+			continue
+		}
+		c, err := parseHexColor(f.value)
+		if err != nil {
+			log.Printf("主题颜色无效，使用默认值: %v", err)
+			continue
+		}
+		*f.dst = c
+	}
+	if out.restBar == (color.RGBA{}) {
+		out.restBar = out.currentBar
+	}
+	return out
+}
+
+// useLightTheme 根据模式和当前时间决定是否使用浅色主题
+func (t ThemeConfig) useLightTheme(now time.Time) bool {
+	switch t.Mode {
+	case themeLight:
+		return true
+	case themeAuto:
+		h := now.Hour()
+		if t.LightFrom <= t.LightUntil {
+			return h >= t.LightFrom && h < t.LightUntil
+		}
+		// 跨越午夜的时段，如 20 点到 6 点
+		return h >= t.LightFrom || h < t.LightUntil
+	}
+	return false
+}
@@ -0,0 +1,7 @@
+//go:build gui && !windows
+// +build gui,!windows
+
+package main
+
+// startTray 托盘图标目前只在 Windows 上提供：macOS 上 systray 和 ebiten 都要求占用主线程
+func startTray() {}
@@ -0,0 +1,122 @@
+//go:build gui && windows
+// +build gui,windows
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"os"
+	"runtime"
+	"time"
+
+	"fyne.io/systray"
+)
+
+// startTray 在窗口版中显示托盘图标，菜单提供暂停/继续、跳过和退出，提示文字每秒更新。
+//
+// 线程: ebiten.RunGame 占用主线程。systray 在 Windows 上只要求创建托盘窗口和处理消息
+// 在同一个系统线程上，并不要求是主线程，所以这里在单独的协程中锁定系统线程后运行
+// systray.Run，两个消息循环各占一个线程，互不阻塞。macOS 上两者都要求主线程，
+// 因此托盘只在 Windows 上启用（见 tray_other.go）
+func startTray() {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("托盘图标崩溃: %v", r)
+			}
+		}()
+		runtime.LockOSThread()
+		systray.Run(runTrayMenu, nil)
+	}()
+}
+
+// runTrayMenu 在托盘就绪后由 systray 调用，一直运行到进程退出
+func runTrayMenu() {
+	systray.SetIcon(trayIcon())
+	systray.SetTooltip(windowTitle)
+	pause := systray.AddMenuItem("暂停", "暂停或继续当前阶段")
+	skip := systray.AddMenuItem("跳过", "立即结束当前阶段")
+	systray.AddSeparator()
+	quit := systray.AddMenuItem("退出", "停止计时并退出")
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	wasPaused := false
+	for {
+		select {
+		case <-ticker.C:
+			// 与窗口读取同一份原子状态
+			st := readStatus()
+			systray.SetTooltip(trayTooltip(st))
+			if st.Paused != wasPaused {
+				wasPaused = st.Paused
+				if wasPaused {
+					pause.SetTitle("继续")
+				} else {
+					pause.SetTitle("暂停")
+				}
+			}
+		case <-pause.ClickedCh:
+			var err error
+			if isPaused() {
+				err = requestResume()
+			} else {
+				err = requestPause()
+			}
+			if err != nil {
+				log.Printf("托盘暂停/继续: %v", err)
+			}
+		case <-skip.ClickedCh:
+			if err := requestSkip(); err != nil {
+				log.Printf("托盘跳过: %v", err)
+			}
+		case <-quit.ClickedCh:
+			// 与 Ctrl+C 相同：停止计时、关闭 Web 服务器和音频，窗口随之关闭
+			shutdown(os.Interrupt)
+			systray.Quit()
+			return
+		}
+	}
+}
+
+// trayTooltip 生成托盘提示文字：当前阶段和剩余时间
+func trayTooltip(st Status) string {
+	tip := fmt.Sprintf("%s - %s %s", windowTitle, st.Phase, formatTime(st.CurrentRemaining()))
+	if st.Paused {
+		tip += "（已暂停）"
+	}
+	return tip
+}
+
+// trayIcon 生成托盘图标：32x32 的番茄色圆点，PNG 格式包在 ICO 文件中（Windows Vista 起支持）
+func trayIcon() []byte {
+	const size = 32
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	tomato := color.NRGBA{R: 0xe5, G: 0x39, B: 0x35, A: 0xff}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx, dy := x-size/2, y-size/2
+			if dx*dx+dy*dy <= (size/2-2)*(size/2-2) {
+				img.Set(x, y, tomato)
+			}
+		}
+	}
+	var pngBuf bytes.Buffer
+	png.Encode(&pngBuf, img)
+
+	var ico bytes.Buffer
+	// ICONDIR: 保留、类型 1（图标）、图像数 1
+	binary.Write(&ico, binary.LittleEndian, [3]uint16{0, 1, 1})
+	// ICONDIRENTRY: 宽、高、调色板、保留、颜色平面、位深、数据长度、数据偏移
+	ico.Write([]byte{size, size, 0, 0})
+	binary.Write(&ico, binary.LittleEndian, [2]uint16{1, 32})
+	binary.Write(&ico, binary.LittleEndian, [2]uint32{uint32(pngBuf.Len()), 6 + 16})
+	ico.Write(pngBuf.Bytes())
+	return ico.Bytes()
+}
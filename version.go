@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync/atomic"
+)
+
+// 以下变量在构建时通过 -ldflags "-X main.version=..." 注入
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+// buildTags 由带构建标签的文件在 init 中登记
+var buildTags []string
+
+// VersionInfo 描述当前程序的版本和启用的功能
+type VersionInfo struct {
+	Version   string          `json:"version"`
+	Commit    string          `json:"commit"`
+	BuildDate string          `json:"build_date"`
+	GoVersion string          `json:"go_version"`
+	BuildTags []string        `json:"build_tags"`
+	Features  map[string]bool `json:"features"`
+}
+
+func versionInfo() VersionInfo {
+	cfg := currentConfig()
+	info := VersionInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		BuildTags: append([]string{}, buildTags...),
+		Features: map[string]bool{
+			"audio":      atomic.LoadInt32(&speakerInited) == 1,
+			"state_file": cfg.StateFile != "" || cfg.StateDir != "",
+		},
+	}
+	sort.Strings(info.BuildTags)
+
+	// 未通过 ldflags 注入时，使用 go build 自动记录的 VCS 信息
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = s.Value
+			}
+		}
+	}
+	return info
+}
+
+func versionString() string {
+	info := versionInfo()
+	return fmt.Sprintf("番茄钟 %s (commit %s, 构建于 %s, %s, 构建标签 %v)",
+		info.Version, info.Commit, info.BuildDate, info.GoVersion, info.BuildTags)
+}
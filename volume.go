@@ -0,0 +1,64 @@
+package main
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/effects"
+)
+
+// volumeLevel 是配置中的音量；配置里用指针区分"没有填写"（默认 1.0）和填写了 0（静音），
+// String 让启动时输出的配置显示数值而不是指针地址
+type volumeLevel float64
+
+func (v volumeLevel) String() string {
+	return strconv.FormatFloat(float64(v), 'f', -1, 64)
+}
+
+// volumeBits 保存当前音量（0.0–1.0）的 float64 位模式，运行时可以通过 /volume 修改
+var volumeBits = math.Float64bits(1)
+
+// clampVolume 把音量限制在 0.0–1.0 之间，无效的值当作最大音量
+func clampVolume(v float64) float64 {
+	switch {
+	case math.IsNaN(v):
+		return 1
+	case v < 0:
+		return 0
+	case v > 1:
+		return 1
+	}
+	return v
+}
+
+// setVolume 设置音量并返回实际使用的值，之后开始播放的提示音使用新音量
+func setVolume(v float64) float64 {
+	c := clampVolume(v)
+	if c != v {
+		log.Printf("音量 %v 超出范围，使用 %v", v, c)
+	}
+	atomic.StoreUint64(&volumeBits, math.Float64bits(c))
+	return c
+}
+
+func currentVolume() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&volumeBits))
+}
+
+// withVolume 按当前音量缩放提示音；音量为 1 时原样返回
+func withVolume(s beep.Streamer) beep.Streamer {
+	v := currentVolume()
+	if v >= 1 {
+		return s
+	}
+	// effects.Volume 的增益为 Base^Volume，以 2 为底换算成线性音量
+	return &effects.Volume{
+		Streamer: s,
+		Base:     2,
+		Volume:   math.Log2(v),
+		Silent:   v == 0,
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 计时器循环卡死时的处理方式
+const (
+	watchdogOff     = ""
+	watchdogLog     = "log"
+	watchdogRestart = "restart"
+	watchdogExit    = "exit"
+)
+
+var (
+	// 计时器循环每次切换阶段或 wait 返回时记录的心跳
+	lastHeartbeatNano int64
+	loopRestarts      int64
+
+	loopCancelMu sync.Mutex
+	loopCancel   context.CancelFunc
+)
+
+func isValidWatchdogAction(action string) bool {
+	switch action {
+	case watchdogOff, watchdogLog, watchdogRestart, watchdogExit:
+		return true
+	}
+	return false
+}
+
+func heartbeat() {
+	atomic.StoreInt64(&lastHeartbeatNano, time.Now().UnixNano())
+}
+
+func lastHeartbeat() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&lastHeartbeatNano))
+}
+
+// newLoopContext 为新的计时器循环创建 context，并取消上一个循环
+func newLoopContext() context.Context {
+	ctx, cancel := context.WithCancel(appCtx)
+	loopCancelMu.Lock()
+	if loopCancel != nil {
+		loopCancel()
+	}
+	loopCancel = cancel
+	loopCancelMu.Unlock()
+	return ctx
+}
+
+// loopStalled 判断计时器循环是否卡死：当前阶段应结束的时间和最后一次心跳中较晚者，
+// 再加上余量仍未有新的心跳。等待确认和暂停中的阶段没有期限，不算卡死
+func loopStalled(now time.Time) bool {
+	phase := getPhase()
+	if phase == phaseIdle || phase == phaseDone || isAwaitingAck() || isPaused() || timerFinished() {
+		return false
+	}
+
+	deadline := lastHeartbeat()
+	t := readTiming()
+	phaseEnd := time.Unix(0, t.cStart+t.cDur)
+	if phaseEnd.After(deadline) {
+		deadline = phaseEnd
+	}
+	margin := time.Duration(currentConfig().WatchdogMarginS) * time.Second
+	return now.After(deadline.Add(margin))
+}
+
+// startWatchdogIfNeeded 在配置了卡死处理时启动看门狗
+func startWatchdogIfNeeded() {
+	if currentConfig().WatchdogAction == watchdogOff {
+		return
+	}
+	go runWatchdog()
+}
+
+func runWatchdog() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("看门狗崩溃: %v", r)
+		}
+	}()
+	cfg := currentConfig()
+	log.Printf("看门狗已启动 (处理方式: %s, 余量: %ds)", cfg.WatchdogAction, cfg.WatchdogMarginS)
+
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		if timerFinished() {
+			return
+		}
+		if !loopStalled(now) {
+			continue
+		}
+
+		msg := fmt.Sprintf("计时器循环疑似卡死: 阶段 %s，最后心跳 %s", getPhase(), lastHeartbeat().Format("15:04:05"))
+		progress("watchdog_stalled", now.Sub(lastHeartbeat()), "%s", msg)
+		log.Println(msg)
+
+		switch currentConfig().WatchdogAction {
+		case watchdogLog:
+			// 只记录一次，直到出现新的心跳
+			heartbeat()
+		case watchdogRestart:
+			atomic.AddInt64(&loopRestarts, 1)
+			log.Println("看门狗: 重新启动计时器循环")
+			clearMesoTask()
+			go timerLoop(newLoopContext())
+		case watchdogExit:
+			log.Println("看门狗: 退出进程")
+			os.Exit(3)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+//go:build web
+// +build web
+
+package main
+
+import (
+	"crypto/subtle"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// middleware 包装一个 http.Handler，用于日志、限流、鉴权等横切逻辑
+type middleware func(http.Handler) http.Handler
+
+// extraMiddlewares 是通过 registerMiddleware 追加的自定义中间件
+var extraMiddlewares []middleware
+
+// registerMiddleware 追加自定义中间件，需在 Web 服务器启动前调用（例如在 init 中）
+func registerMiddleware(mw middleware) {
+	extraMiddlewares = append(extraMiddlewares, mw)
+}
+
+// chain 依次套用中间件，列表中第一个位于最外层
+func chain(h http.Handler, mws ...middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// webMiddlewares 根据配置组装中间件链，默认只有允许任意来源的跨域中间件
+func webMiddlewares() []middleware {
+	cfg := currentConfig()
+	var mws []middleware
+	if cfg.WebRequestLog {
+		mws = append(mws, requestLogMiddleware)
+	}
+	if len(cfg.WebAllowOrigins) > 0 {
+		mws = append(mws, corsMiddleware(cfg.WebAllowOrigins))
+	}
+	if cfg.WebRateLimit > 0 {
+		mws = append(mws, rateLimitMiddleware(cfg.WebRateLimit))
+	}
+	// 认证放在限流之后，猜密码的请求同样受限
+	if cfg.WebUsername != "" {
+		mws = append(mws, basicAuthMiddleware(cfg.WebUsername, cfg.WebPassword))
+	}
+	return append(mws, extraMiddlewares...)
+}
+
+// basicAuthMiddleware 要求 HTTP Basic 认证，用户名和密码都以固定时间比较
+func basicAuthMiddleware(username, password string) middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, pass, ok := r.BasicAuth()
+			userOK := subtle.ConstantTimeCompare([]byte(user), []byte(username)) == 1
+			passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(password)) == 1
+			if !ok || !userOK || !passOK {
+				w.Header().Set("WWW-Authenticate", `Basic realm="fanqiezhong", charset="UTF-8"`)
+				http.Error(w, "需要登录", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// corsMiddleware 允许配置的来源跨域读取响应，并直接应答 OPTIONS 预检请求。
+// 列表中有 "*" 时对任意来源返回 *，否则只回显列表中的来源
+func corsMiddleware(origins []string) middleware {
+	allowAll := slices.Contains(origins, "*")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			allowed := origin != "" && (allowAll || slices.Contains(origins, strings.TrimSuffix(origin, "/")))
+			if allowed {
+				if allowAll {
+					w.Header().Set("Access-Control-Allow-Origin", "*")
+				} else {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+					w.Header().Add("Vary", "Origin")
+				}
+			}
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				if allowed {
+					w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+					w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+					w.Header().Set("Access-Control-Max-Age", "600")
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// statusRecorder 记录响应状态码，供日志使用
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap 让 http.ResponseController 能找到底层的 ResponseWriter（如 Flush）
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func requestLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		log.Printf("%s %s %s %d %v", clientIP(r), r.Method, r.URL.Path, rec.status, time.Since(start).Round(time.Millisecond))
+	})
+}
+
+// rateLimiter 按客户端 IP 统计每秒请求数，每秒换一个窗口，内存占用有上限
+type rateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window int64
+	counts map[string]int
+}
+
+func (rl *rateLimiter) allow(ip string) bool {
+	now := time.Now().Unix()
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if now != rl.window {
+		rl.window = now
+		rl.counts = make(map[string]int)
+	}
+	rl.counts[ip]++
+	return rl.counts[ip] <= rl.limit
+}
+
+func rateLimitMiddleware(perSecond int) middleware {
+	rl := &rateLimiter{limit: perSecond}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !rl.allow(clientIP(r)) {
+				http.Error(w, "请求过于频繁", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
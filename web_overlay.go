@@ -0,0 +1,79 @@
+//go:build web
+// +build web
+
+package main
+
+import (
+	"io/fs"
+	"net/http"
+)
+
+// webRootHandler 在 / 提供嵌入的 web 目录（原来的 /web/ 地址仍然可用）；
+// 目录中没有 index.html 时在 / 返回内置的简易挂件，而不是空白的目录列表
+func webRootHandler() http.Handler {
+	root, err := fs.Sub(webFS, "web")
+	if err != nil {
+		root = webFS
+	}
+	files := http.FileServer(http.FS(root))
+	_, indexErr := fs.Stat(root, "index.html")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" && indexErr != nil {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(defaultOverlayHTML))
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+// defaultOverlayHTML 是内置的简易挂件：两条进度条和剩余时间，每秒轮询 /status
+const defaultOverlayHTML = `<!DOCTYPE html>
+<html lang="zh">
+<head>
+<meta charset="UTF-8">
+<title>番茄钟</title>
+<style>
+    :root { --background: #000; --text: #fff; --bar-background: #333; --current-bar: #4CAF50; --rest-bar: #4CAF50; --meso-bar: #2196F3; }
+    body { margin: 0; padding: 10px; background: var(--background); color: var(--text); font-family: sans-serif; }
+    .row { display: flex; align-items: center; gap: 10px; margin-bottom: 10px; }
+    .track { flex: 1; height: 24px; background: var(--bar-background); border-radius: 4px; overflow: hidden; }
+    .bar { height: 100%; width: 0; background: var(--current-bar); }
+    .resting #current { background: var(--rest-bar); }
+    #meso { background: var(--meso-bar); }
+    .time { min-width: 70px; text-align: right; font-weight: bold; font-variant-numeric: tabular-nums; }
+    .hidden { display: none; }
+</style>
+</head>
+<body>
+<div class="row"><div class="track"><div class="bar" id="current"></div></div><div class="time" id="current-time">--:--</div></div>
+<div class="row hidden" id="meso-row"><div class="track"><div class="bar" id="meso"></div></div><div class="time" id="meso-time">--:--</div></div>
+<script>
+    function fmt(s) {
+        s = Math.max(0, Math.ceil(s));
+        const pad = n => String(n).padStart(2, '0');
+        return pad(Math.floor(s / 60)) + ':' + pad(s % 60);
+    }
+    function show(bar, label, elapsed, total) {
+        document.getElementById(bar).style.width = (total > 0 ? Math.min(100, elapsed / total * 100) : 0) + '%';
+        document.getElementById(label).textContent = fmt(total - elapsed);
+    }
+    async function update() {
+        try {
+            const data = await (await fetch('/status')).json();
+            for (const [name, value] of Object.entries(data.colors || {})) {
+                document.documentElement.style.setProperty('--' + name.replace('_', '-'), value);
+            }
+            document.body.classList.toggle('resting', data.resting);
+            show('current', 'current-time', data.current_elapsed, data.current_total);
+            document.getElementById('meso-row').classList.toggle('hidden', !data.in_meso);
+            show('meso', 'meso-time', data.meso_elapsed, data.meso_total);
+        } catch (e) {}
+    }
+    update();
+    setInterval(update, 1000);
+</script>
+</body>
+</html>
+`
@@ -4,10 +4,19 @@
 package main
 
 import (
+	"context"
+	"crypto/subtle"
 	"embed"
 	"encoding/json"
 	"fmt"
+	"io/fs"
+	"math"
+	"net"
 	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -15,55 +24,625 @@ import (
 //go:embed web/*
 var webFS embed.FS
 
+func init() {
+	buildTags = append(buildTags, "web")
+}
+
+var (
+	webServerMu sync.Mutex
+	webServer   *http.Server
+)
+
 func startWebServerIfNeeded() {
-	addr := fmt.Sprintf("0.0.0.0:%d", config.Port)
-	go startWebServer(addr)
+	if err := startWebServer(webAddr()); err != nil {
+		progress("error", 0, "Web 服务器启动失败: %v", err)
+	}
 }
 
-func startWebServer(addr string) {
-	// 使用嵌入的文件系统
-	http.Handle("/", http.FileServer(http.FS(webFS)))
-	http.HandleFunc("/status", statusHandler)
+// restartWebServer 在重新读取配置后端口或监听地址改变时，关闭旧的服务器并在新地址上启动
+func restartWebServer() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := stopWebServer(ctx); err != nil {
+		progress("error", 0, "关闭 Web 服务器失败: %v", err)
+	}
+	startWebServerIfNeeded()
+}
 
-	fmt.Printf("Web UI 服务器已启动: http://%s\n", addr)
-	fmt.Println("你可以将此地址添加为 OBS 的浏览器源。")
+func webAddr() string {
+	cfg := currentConfig()
+	return net.JoinHostPort(cfg.BindAddress, strconv.Itoa(cfg.Port))
+}
 
-	if err := http.ListenAndServe(addr, nil); err != nil {
-		fmt.Printf("Web 服务器启动失败: %v\n", err)
+func webSelfChecks() []selfCheck {
+	return []selfCheck{
+		{"Web 端口", checkWebPort},
+		{"Web 页面资源", checkWebAssets},
 	}
 }
 
+// checkWebPort 确认端口当前可以绑定
+func checkWebPort() error {
+	ln, err := net.Listen("tcp", webAddr())
+	if err != nil {
+		return err
+	}
+	return ln.Close()
+}
+
+// checkWebAssets 确认页面已经嵌入到程序中
+func checkWebAssets() error {
+	_, err := fs.Stat(webFS, "web/index.html")
+	return err
+}
+
+// newWebHandler 在独立的 ServeMux 上注册全部路由并套上中间件链，
+// 不使用全局 DefaultServeMux，重复创建不会因重复注册而 panic
+func newWebHandler() http.Handler {
+	cfg := currentConfig()
+	mux := http.NewServeMux()
+	// 使用嵌入的文件系统：页面在 /，/web/ 为原来的地址
+	mux.Handle("/", webRootHandler())
+	mux.Handle("/web/", http.FileServer(http.FS(webFS)))
+	mux.HandleFunc("/status", statusHandler)
+	mux.HandleFunc("/version", versionHandler)
+	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("/chart.txt", chartHandler)
+	mux.HandleFunc("/stats", statsHandler)
+	mux.HandleFunc("/config/effective", effectiveConfigHandler)
+	mux.HandleFunc("/config/share-link", shareLinkHandler)
+	mux.HandleFunc("/config/share", shareImportHandler)
+	mux.HandleFunc("/preset", presetHandler)
+	mux.HandleFunc("/control/skip", skipHandler)
+	mux.HandleFunc("/control/end-meso", endMesoHandler)
+	mux.HandleFunc("/control/ack", ackHandler)
+	mux.HandleFunc("/control/quickfocus", quickFocusHandler)
+	mux.HandleFunc("/control/pause", pauseHandler)
+	mux.HandleFunc("/control/resume", resumeHandler)
+	mux.HandleFunc("/sound-themes", soundThemesHandler)
+	mux.HandleFunc("/control/sound-theme", soundThemeHandler)
+	mux.HandleFunc("/volume", volumeHandler)
+	mux.HandleFunc("/sounds/reload", soundsReloadHandler)
+	mux.HandleFunc("/events", eventsHandler)
+	mux.HandleFunc("/ws", wsHandler)
+	if cfg.WebLogLines > 0 {
+		mux.HandleFunc("/logs", logsHandler)
+	}
+	if cfg.WebClientSound {
+		mux.Handle("/Sounds/", http.StripPrefix("/Sounds/", http.FileServer(http.Dir("Sounds"))))
+	}
+	if cfg.Debug {
+		mux.HandleFunc("/debug/resources", resourcesHandler)
+		mux.HandleFunc("/debug/plan", planDebugHandler)
+	}
+
+	return chain(mux, webMiddlewares()...)
+}
+
+// startWebServer 为每次启动创建新的 http.Server，停止后可以再次启动
+func startWebServer(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Addr: addr, Handler: newWebHandler()}
+	// 关闭时取消请求的 context，让 /events 这类长连接立即结束
+	baseCtx, cancel := context.WithCancel(context.Background())
+	srv.BaseContext = func(net.Listener) context.Context { return baseCtx }
+	srv.RegisterOnShutdown(cancel)
+
+	webServerMu.Lock()
+	webServer = srv
+	webServerMu.Unlock()
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			progress("error", 0, "Web 服务器异常退出: %v", err)
+		}
+	}()
+
+	if !currentConfig().QuietStart {
+		progress("web_started", 0, "Web UI 服务器已启动: http://%s", addr)
+		progress("web_started", 0, "你可以将此地址添加为 OBS 的浏览器源。")
+	}
+	return nil
+}
+
+// stopWebServer 优雅关闭当前的 Web 服务器，等待进行中的请求完成
+func stopWebServer(ctx context.Context) error {
+	webServerMu.Lock()
+	srv := webServer
+	webServer = nil
+	webServerMu.Unlock()
+
+	if srv == nil {
+		return nil
+	}
+	return srv.Shutdown(ctx)
+}
+
 func statusHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statusPayload(r.URL.Query().Get("raw") == "1"))
+}
+
+// statusPayload 生成 /status 和 /ws 推送的状态内容，raw 为 true 时不取整
+func statusPayload(raw bool) map[string]interface{} {
+	cfg := currentConfig()
 	// 无锁读取原子变量
-	now := time.Now().UnixNano()
+	st := readStatus()
+
+	if cfg.WebStatusWholeSeconds && !raw {
+		st = st.wholeSeconds()
+	}
+
+	resp := map[string]interface{}{
+		"phase":            st.Phase,
+		"resting":          isRestPhase(st.Phase),
+		"current_total":    st.CurrentTotal,
+		"current_elapsed":  st.CurrentElapsed,
+		"in_meso":          st.InMeso,
+		"meso_total":       st.MesoTotal,
+		"meso_elapsed":     st.MesoElapsed,
+		"streak":           st.Streak,
+		"awaiting_ack":     st.AwaitingAck,
+		"paused":           st.Paused,
+		"active_skips":     st.ActiveSkips,
+		"meso_ended_early": st.MesoEndedEarly,
+		"skips_remaining":  st.SkipsRemaining,
+
+		"quick_focus_count":   st.QuickFocusCount,
+		"quick_focus_seconds": st.QuickFocusSeconds,
+
+		"makeup_count":   st.MakeupCount,
+		"makeup_seconds": st.MakeupSeconds,
+
+		"total_focus_seconds": st.TotalFocusSeconds,
+
+		// 页面据此决定一小时以上的时间是否显示为 HH:MM:SS
+		"show_hours": cfg.ShowHours,
+
+		// 与窗口相同的配色（随 主题 的模式和时间切换），页面不需要自己实现主题逻辑
+		"colors": currentPalette().colorHints(),
+
+		// 服务器读取状态的时间（Unix 毫秒），客户端据此在两次轮询之间插值
+		"server_time": st.At.UnixMilli(),
+	}
+	// 最近一次事件：轮询的客户端可以比较 cue_seq 判断是否有新的提示
+	if c := getLastCue(); c.Seq > 0 {
+		resp["cue_seq"] = c.Seq
+		resp["cue_event"] = c.Event
+	}
+	if id := currentSessionID(); id != "" {
+		resp["session_id"] = id
+	}
+	resp["planned_macro_seconds"] = st.PlannedMacroSeconds
+	if st.HasPlannedSession {
+		resp["planned_session_seconds"] = st.PlannedSessionSeconds
+	}
+	if st.HasDailyBudget {
+		resp["daily_budget_remaining"] = st.DailyBudgetRemaining
+	}
+	if cfg.ShowCycleTiming && st.LastCycle.Phase != "" {
+		resp["last_phase"] = st.LastCycle.Phase
+		resp["last_planned_seconds"] = st.LastCycle.Planned.Seconds()
+		resp["last_actual_seconds"] = st.LastCycle.Actual.Seconds()
+	}
+	return resp
+}
+
+// eventsHandler 以 Server-Sent Events 推送事件提示，每个事件一条 cue 消息。
+// 开启 Web客户端提示音 时 play_sound 为 true，浏览器据此播放 sound_url
+func eventsHandler(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := rc.Flush(); err != nil {
+		http.Error(w, "不支持推送", http.StatusInternalServerError)
+		return
+	}
+
+	ch := subscribeCues()
+	defer unsubscribeCues(ch)
+
+	// ?status=1 时同时推送 status 消息，内容与 /status 相同：每秒一次，计时状态变化时立即推送
+	var statusC <-chan time.Time
+	if r.URL.Query().Get("status") == "1" {
+		check := time.NewTicker(wsCheckTick)
+		defer check.Stop()
+		statusC = check.C
+		writeStatusEvent(w)
+		rc.Flush()
+	}
+	lastSeq, lastSent := atomic.LoadUint64(&timingSeq), time.Now()
+
+	// 定期发送注释行，避免代理因空闲断开连接
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case c := <-ch:
+			data, _ := json.Marshal(c)
+			fmt.Fprintf(w, "id: %d\nevent: cue\ndata: %s\n\n", c.Seq, data)
+		case now := <-statusC:
+			seq := atomic.LoadUint64(&timingSeq)
+			if seq == lastSeq && now.Sub(lastSent) < wsTick {
+				continue
+			}
+			lastSeq, lastSent = seq, now
+			writeStatusEvent(w)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeStatusEvent 写出一条 status 消息
+func writeStatusEvent(w http.ResponseWriter) {
+	data, _ := json.Marshal(statusPayload(false))
+	fmt.Fprintf(w, "event: status\ndata: %s\n\n", data)
+}
+
+// logsHandler 返回最近的日志（JSON），?follow=1 时改为以 Server-Sent Events 持续推送新日志。
+// 需要在 Authorization: Bearer 或 ?token= 中提供 Web日志令牌
+func logsHandler(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(currentConfig().WebLogToken)) != 1 {
+		http.Error(w, "需要 Web日志令牌", http.StatusUnauthorized)
+		return
+	}
+
+	if r.URL.Query().Get("follow") != "1" {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.Encode(map[string]interface{}{"lines": recentLogs()})
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := rc.Flush(); err != nil {
+		http.Error(w, "不支持推送", http.StatusInternalServerError)
+		return
+	}
+
+	ch := subscribeLogs()
+	defer unsubscribeLogs(ch)
+
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case l := <-ch:
+			data, _ := json.Marshal(l)
+			fmt.Fprintf(w, "id: %d\nevent: log\ndata: %s\n\n", l.Seq, data)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// statsHandler 返回今天历史记录的汇总，没有历史记录时各项为 0
+func statsHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := dayStats(time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
 
-	cStart := atomic.LoadInt64(&currentStartNano)
-	cDur := atomic.LoadInt64(&currentDuration)
-	mStart := atomic.LoadInt64(&mesoStartNano)
-	mDur := atomic.LoadInt64(&mesoDuration)
-	inMesoFlag := atomic.LoadInt32(&inMeso) == 1
+// healthHandler 报告计时器循环的心跳，卡死时返回 503 便于外部监控
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	hb := lastHeartbeat()
+	stalled := loopStalled(now)
 
-	// 计算时间值
-	currentElapsed := float64(now-cStart) / 1e9
-	cTotalSec := float64(cDur) / 1e9
-	if currentElapsed > cTotalSec {
-		currentElapsed = cTotalSec
+	resp := map[string]interface{}{
+		"status":                "ok",
+		"phase":                 getPhase(),
+		"last_heartbeat":        hb.Format(time.RFC3339),
+		"heartbeat_age_seconds": now.Sub(hb).Seconds(),
+		"loop_restarts":         atomic.LoadInt64(&loopRestarts),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if stalled {
+		resp["status"] = "stalled"
+		w.WriteHeader(http.StatusServiceUnavailable)
 	}
+	json.NewEncoder(w).Encode(resp)
+}
 
-	mesoElapsed := float64(now-mStart) / 1e9
-	mTotalSec := float64(mDur) / 1e9
-	if mesoElapsed > mTotalSec {
-		mesoElapsed = mTotalSec
+// chartHandler 以纯文本返回计划图表，可用 ?width= 指定宽度
+func chartHandler(w http.ResponseWriter, r *http.Request) {
+	width := defaultChartWidth
+	if v := r.URL.Query().Get("width"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "width 必须是整数", http.StatusBadRequest)
+			return
+		}
+		width = n
 	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, renderScheduleChart(width))
+}
+
+func versionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versionInfo())
+}
+
+// effectiveConfigHandler 返回正在使用的配置（已补全默认值，隐去 Webhook 令牌）
+func effectiveConfigHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	enc.Encode(currentConfig().redacted())
+}
+
+// shareLinkHandler 返回当前时间安排的分享码和导入地址
+func shareLinkHandler(w http.ResponseWriter, r *http.Request) {
+	code, err := encodeShareCode(*currentBaseConfig())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"code": code,
+		"url":  scheme + "://" + r.Host + "/config/share?c=" + code,
+	})
+}
+
+// shareImportHandler 处理分享码：GET 预览其中的时间安排，POST 校验后在下一个大循环开始时换用；
+// 分享码无效时返回 400。导入的设置不写回 config.json，重启后恢复原配置
+func shareImportHandler(w http.ResponseWriter, r *http.Request) {
+	c, err := decodeShareCode(r.URL.Query().Get("c"), *currentBaseConfig())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		fields, err := sharedFields(c)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		enc.Encode(fields)
+	case http.MethodPost:
+		warnConfig(c)
+		queuePendingConfig(c)
+		progress("config_pending", 0, "已导入分享的配置，新的时间安排将在下一个大循环开始时生效")
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		http.Error(w, "仅支持 GET 和 POST", http.StatusMethodNotAllowed)
+	}
+}
+
+// presetHandler GET 返回全部预设和当前预设，POST ?name= 换用指定的预设（空名称表示不使用预设），
+// 在下一个大循环开始时生效；不写回 config.json
+func presetHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		base, name, ok := presetState()
+		resp := map[string]interface{}{
+			"active":  base.ActivePreset,
+			"presets": presetNames(*base),
+		}
+		if ok && name != base.ActivePreset {
+			resp["pending"] = name
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.Encode(resp)
+	case http.MethodPost:
+		name := r.URL.Query().Get("name")
+		if err := queuePreset(name); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		progress("config_pending", 0, "将换用预设 %s，在下一个大循环开始时生效", presetLabel(name))
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		http.Error(w, "仅支持 GET 和 POST", http.StatusMethodNotAllowed)
+	}
+}
+
+func skipHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持 POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := requestSkip(); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func ackHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持 POST", http.StatusMethodNotAllowed)
+		return
+	}
+	phase, err := requestAck()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"phase": phase})
+}
+
+// resourcesHandler 报告协程数、内存和音频播放情况，用于确认常驻运行时资源占用正常
+func resourcesHandler(w http.ResponseWriter, r *http.Request) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
 
 	resp := map[string]interface{}{
-		"current_total":   cTotalSec,
-		"current_elapsed": currentElapsed,
-		"in_meso":         inMesoFlag,
-		"meso_total":      mTotalSec,
-		"meso_elapsed":    mesoElapsed,
+		"goroutines":      runtime.NumGoroutine(),
+		"heap_alloc":      m.HeapAlloc,
+		"heap_inuse":      m.HeapInuse,
+		"heap_objects":    m.HeapObjects,
+		"sys":             m.Sys,
+		"total_alloc":     m.TotalAlloc,
+		"num_gc":          m.NumGC,
+		"gc_pause_total":  time.Duration(m.PauseTotalNs).String(),
+		"audio_available": atomic.LoadInt32(&speakerInited) == 1,
+		"audio_playing":   atomic.LoadInt32(&soundsPlaying) > 0,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
+
+// planDebugHandler 返回当前中循环的规划细节，还没有开始中循环时为 null
+func planDebugHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(currentMesoPlan())
+}
+
+func endMesoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持 POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := requestEndMeso(); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// quickFocusHandler 处理 POST /control/quickfocus?minutes=25
+func quickFocusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持 POST", http.StatusMethodNotAllowed)
+		return
+	}
+	minutes, err := strconv.Atoi(r.URL.Query().Get("minutes"))
+	if err != nil || minutes < 1 || minutes > 180 {
+		http.Error(w, "minutes 必须是 1-180 之间的整数", http.StatusBadRequest)
+		return
+	}
+	if err := requestQuickFocus(time.Duration(minutes) * time.Minute); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// pauseHandler 暂停当前阶段；没有可以暂停的阶段或已经暂停时返回 409
+func pauseHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持 POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := requestPause(); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// resumeHandler 继续已暂停的阶段；没有暂停时返回 409
+func resumeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持 POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := requestResume(); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// soundThemesHandler 列出可用的提示音主题和当前主题
+func soundThemesHandler(w http.ResponseWriter, r *http.Request) {
+	themes, err := listSoundThemes()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if themes == nil {
+		themes = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"active": activeSoundTheme(),
+		"themes": themes,
+	})
+}
+
+// volumeHandler 在运行时设置提示音音量（?value=0.0–1.0，超出范围时取最近的值），返回实际音量
+func volumeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持 POST", http.StatusMethodNotAllowed)
+		return
+	}
+	v, err := strconv.ParseFloat(r.URL.Query().Get("value"), 64)
+	if err != nil || math.IsNaN(v) {
+		http.Error(w, "value 必须是 0.0-1.0 之间的数", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]float64{"volume": setVolume(v)})
+}
+
+// soundsReloadHandler 重新解码全部提示音，返回重新加载的文件 reloaded 和失败原因 failed
+func soundsReloadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持 POST", http.StatusMethodNotAllowed)
+		return
+	}
+	reloaded, failed := reloadSounds()
+	if reloaded == nil {
+		reloaded = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(map[string]interface{}{"reloaded": reloaded, "failed": failed})
+}
+
+// soundThemeHandler 处理 POST /control/sound-theme?name=bells，name 为空时切回默认提示音
+func soundThemeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持 POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := setSoundTheme(r.URL.Query().Get("name")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
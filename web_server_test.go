@@ -0,0 +1,196 @@
+//go:build web
+// +build web
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// serve 用当前配置组装的完整处理链（含中间件）处理一个请求
+func serve(t *testing.T, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	newWebHandler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestStatusFields(t *testing.T) {
+	c := testSchedule()
+	c.ShowHours = true
+	useTestConfig(t, c)
+	setCurrentTask(phaseMicroFocus, time.Minute)
+
+	rec := serve(t, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /status 返回 %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("无法解析响应: %v", err)
+	}
+	for _, key := range []string{"phase", "resting", "current_total", "current_elapsed", "in_meso",
+		"meso_total", "meso_elapsed", "awaiting_ack", "paused", "skips_remaining", "show_hours", "colors"} {
+		if _, ok := body[key]; !ok {
+			t.Errorf("响应缺少字段 %s", key)
+		}
+	}
+	if body["phase"] != phaseMicroFocus {
+		t.Errorf("phase = %v，应为 %s", body["phase"], phaseMicroFocus)
+	}
+	if body["current_total"] != 60.0 {
+		t.Errorf("current_total = %v，应为 60", body["current_total"])
+	}
+	if body["resting"] != false || body["show_hours"] != true {
+		t.Errorf("resting = %v，show_hours = %v", body["resting"], body["show_hours"])
+	}
+}
+
+func TestControlStatusCodes(t *testing.T) {
+	useTestConfig(t, testSchedule())
+	setCurrentTask(phaseMicroFocus, time.Minute)
+
+	for _, tc := range []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/control/skip", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/control/pause", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/control/resume", http.StatusConflict},
+		{http.MethodPost, "/control/ack", http.StatusConflict},
+		{http.MethodPost, "/control/quickfocus?minutes=0", http.StatusBadRequest},
+		{http.MethodPost, "/volume?value=abc", http.StatusBadRequest},
+		{http.MethodGet, "/health", http.StatusOK},
+	} {
+		rec := serve(t, httptest.NewRequest(tc.method, tc.path, nil))
+		if rec.Code != tc.want {
+			t.Errorf("%s %s 返回 %d，应为 %d", tc.method, tc.path, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestBasicAuthRejects(t *testing.T) {
+	c := testSchedule()
+	c.WebUsername, c.WebPassword = "user", "secret"
+	useTestConfig(t, c)
+
+	for _, tc := range []struct {
+		name       string
+		user, pass string
+		auth       bool
+		want       int
+	}{
+		{"没有认证", "", "", false, http.StatusUnauthorized},
+		{"密码错误", "user", "wrong", true, http.StatusUnauthorized},
+		{"用户名错误", "other", "secret", true, http.StatusUnauthorized},
+		{"正确", "user", "secret", true, http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/status", nil)
+			if tc.auth {
+				req.SetBasicAuth(tc.user, tc.pass)
+			}
+			rec := serve(t, req)
+			if rec.Code != tc.want {
+				t.Fatalf("返回 %d，应为 %d", rec.Code, tc.want)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 响应缺少 WWW-Authenticate")
+			}
+		})
+	}
+}
+
+func TestCORSHeaders(t *testing.T) {
+	t.Run("默认允许任意来源", func(t *testing.T) {
+		useTestConfig(t, testSchedule())
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		req.Header.Set("Origin", "https://obs.example")
+		if got := serve(t, req).Header().Get("Access-Control-Allow-Origin"); got != "*" {
+			t.Errorf("Access-Control-Allow-Origin = %q，应为 *", got)
+		}
+	})
+
+	t.Run("只回显列表中的来源", func(t *testing.T) {
+		c := testSchedule()
+		c.WebAllowOrigins = []string{"https://a.example"}
+		useTestConfig(t, c)
+
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		req.Header.Set("Origin", "https://a.example")
+		rec := serve(t, req)
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://a.example" {
+			t.Errorf("Access-Control-Allow-Origin = %q", got)
+		}
+		if rec.Header().Get("Vary") != "Origin" {
+			t.Errorf("Vary = %q，应为 Origin", rec.Header().Get("Vary"))
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "/status", nil)
+		req.Header.Set("Origin", "https://b.example")
+		if got := serve(t, req).Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("未列出的来源得到了 Access-Control-Allow-Origin = %q", got)
+		}
+	})
+
+	t.Run("预检请求", func(t *testing.T) {
+		useTestConfig(t, testSchedule())
+		req := httptest.NewRequest(http.MethodOptions, "/control/skip", nil)
+		req.Header.Set("Origin", "https://obs.example")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		rec := serve(t, req)
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("预检返回 %d，应为 204", rec.Code)
+		}
+		if rec.Header().Get("Access-Control-Allow-Methods") == "" {
+			t.Error("预检响应缺少 Access-Control-Allow-Methods")
+		}
+	})
+}
+
+// TestWebServerRestart 在同一地址上两次启动和关闭服务器，每次都使用新的处理链
+func TestWebServerRestart(t *testing.T) {
+	useTestConfig(t, testSchedule())
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	client := &http.Client{Timeout: time.Second}
+	for round := 1; round <= 2; round++ {
+		if err := startWebServer(addr); err != nil {
+			t.Fatalf("第 %d 次启动失败: %v", round, err)
+		}
+		resp, err := client.Get("http://" + addr + "/health")
+		if err != nil {
+			t.Fatalf("第 %d 次启动后请求失败: %v", round, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("第 %d 次启动后 /health 返回 %d", round, resp.StatusCode)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		err = stopWebServer(ctx)
+		cancel()
+		if err != nil {
+			t.Fatalf("第 %d 次关闭失败: %v", round, err)
+		}
+		if resp, err := client.Get("http://" + addr + "/health"); err == nil {
+			resp.Body.Close()
+			t.Fatalf("第 %d 次关闭后服务器仍在响应", round)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+//go:build web
+// +build web
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"time"
+)
+
+var setupPage = template.Must(template.New("setup").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="UTF-8">
+    <title>番茄钟首次配置</title>
+    <style>
+        body { background-color: #000; color: #fff; font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif; padding: 20px; }
+        label { display: block; margin: 10px 0 4px; }
+        input { width: 120px; font-size: 16px; }
+        button { margin-top: 16px; font-size: 16px; }
+        .error { color: #f44336; }
+    </style>
+</head>
+<body>
+    <h2>番茄钟首次配置</h2>
+    {{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+    <form method="post" action="/setup">
+        {{range .Fields}}
+        <label for="{{.Key}}">{{.Prompt}}</label>
+        <input type="number" id="{{.Key}}" name="{{.Key}}" value="{{.Default}}" min="{{.Min}}" max="{{.Max}}" required>
+        {{end}}
+        <br><button type="submit">保存并开始</button>
+    </form>
+</body>
+</html>
+`))
+
+func webSetupAvailable() bool {
+	return true
+}
+
+// runWebSetup 在默认端口上临时提供配置页面，提交有效配置后关闭并返回
+func runWebSetup() (Config, error) {
+	addr := fmt.Sprintf("0.0.0.0:%d", defaultPort)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return Config{}, err
+	}
+
+	done := make(chan Config, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		setupPage.Execute(w, map[string]interface{}{"Fields": setupFields})
+	})
+	mux.HandleFunc("/setup", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+		r.ParseForm()
+		c, err := setupFromValues(r.PostForm.Get)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			setupPage.Execute(w, map[string]interface{}{"Fields": setupFields, "Error": err.Error()})
+			return
+		}
+		fmt.Fprintf(w, "配置已保存，番茄钟即将在 http://localhost:%d 启动。", c.Port)
+		select {
+		case done <- c:
+		default:
+		}
+	})
+
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+	progress("setup_web", 0, "首次使用，请在浏览器中打开 http://localhost:%d 完成配置", defaultPort)
+
+	c := <-done
+	// 留出时间把响应发送给浏览器
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	srv.Shutdown(ctx)
+	return c, nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// webhookPayload 是每次事件 POST 给 Webhook 的 JSON
+type webhookPayload struct {
+	Event     string    `json:"event"`
+	Phase     string    `json:"phase"`
+	SessionID string    `json:"session_id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// deadLetter 是最终发送失败、写入失败记录文件的一条 Webhook
+type deadLetter struct {
+	URL      string         `json:"url"`
+	Payload  webhookPayload `json:"payload"`
+	Error    string         `json:"error"`
+	FailedAt time.Time      `json:"failed_at"`
+}
+
+var deadLetterMu sync.Mutex
+
+// maxWebhookBackoff 是重试间隔翻倍的上限，重试次数较多时不会等上几十分钟
+const maxWebhookBackoff = time.Minute
+
+// sendWebhook 在后台发送事件，慢速或失败的 Webhook 不会拖住计时器
+func sendWebhook(event string) {
+	cfg := currentConfig()
+	if cfg.WebhookURL == "" || !webhookWanted(event) {
+		return
+	}
+	payload := webhookPayload{
+		Event:     event,
+		Phase:     getPhase(),
+		SessionID: currentSessionID(),
+		Timestamp: time.Now(),
+	}
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Webhook 发送崩溃: %v", r)
+			}
+		}()
+		if err := deliverWebhook(cfg.WebhookURL, payload); err != nil {
+			log.Printf("Webhook 发送失败 (%s): %v", event, err)
+			recordDeadLetter(cfg.WebhookURL, payload, err)
+		}
+	}()
+}
+
+// webhookWanted 按配置的事件白名单过滤，白名单为空时发送全部事件
+func webhookWanted(event string) bool {
+	cfg := currentConfig()
+	if len(cfg.WebhookEvents) == 0 {
+		return true
+	}
+	for _, e := range cfg.WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// deliverWebhook 发送一次，失败后按配置的次数重试，每次重试的间隔翻倍，最长 maxWebhookBackoff
+func deliverWebhook(url string, payload webhookPayload) error {
+	cfg := currentConfig()
+	client := &http.Client{Timeout: time.Duration(cfg.WebhookTimeoutS) * time.Second}
+	backoff := min(time.Duration(cfg.WebhookBackoffMs)*time.Millisecond, maxWebhookBackoff)
+
+	var err error
+	for attempt := 0; attempt <= cfg.WebhookRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff = min(backoff*2, maxWebhookBackoff)
+		}
+		if err = postWebhook(client, url, payload); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("重试 %d 次后仍失败: %w", cfg.WebhookRetries, err)
+}
+
+func postWebhook(client *http.Client, url string, payload webhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// recordDeadLetter 把最终失败的 Webhook 追加到失败记录文件，便于之后重放。
+// 文件超过上限时改名为 .old（覆盖上一份）后重新开始
+func recordDeadLetter(url string, payload webhookPayload, sendErr error) {
+	cfg := currentConfig()
+	path := cfg.WebhookDeadLetter
+	if path == "" {
+		return
+	}
+	line, err := json.Marshal(deadLetter{
+		URL:      url,
+		Payload:  payload,
+		Error:    sendErr.Error(),
+		FailedAt: time.Now(),
+	})
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	deadLetterMu.Lock()
+	defer deadLetterMu.Unlock()
+
+	limit := int64(cfg.WebhookDeadLetterMaxKB) * 1024
+	if fi, err := os.Stat(path); err == nil && fi.Size()+int64(len(line)) > limit {
+		if err := os.Rename(path, path+".old"); err != nil {
+			log.Printf("轮换 Webhook 失败记录文件失败: %v", err)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("写入 Webhook 失败记录失败: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(line); err != nil {
+		log.Printf("写入 Webhook 失败记录失败: %v", err)
+	}
+}
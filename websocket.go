@@ -0,0 +1,251 @@
+//go:build web
+// +build web
+
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// wsGUID 是 RFC 6455 规定的握手常量
+	wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	wsOpText  = 0x1
+	wsOpClose = 0x8
+	wsOpPing  = 0x9
+	wsOpPong  = 0xA
+
+	// 客户端只会发送控制帧，超过该长度的帧直接断开
+	wsMaxReadPayload = 4096
+
+	// 每秒至少推送一次；计时状态变化时在下一个检查点立即推送
+	wsTick      = time.Second
+	wsCheckTick = 200 * time.Millisecond
+)
+
+// wsFrame 是等待发送的一帧
+type wsFrame struct {
+	op   byte
+	data []byte
+}
+
+// wsClient 是一个已连接的 WebSocket 客户端，写入只在它自己的协程中进行
+type wsClient struct {
+	conn net.Conn
+	send chan wsFrame
+	once sync.Once
+}
+
+func (c *wsClient) close() {
+	c.once.Do(func() { c.conn.Close() })
+}
+
+// wsHub 把状态广播给全部客户端
+var wsHub = struct {
+	sync.Mutex
+	clients map[*wsClient]struct{}
+	once    sync.Once
+}{clients: map[*wsClient]struct{}{}}
+
+// wsHandler 完成 WebSocket 握手后持续推送与 /status 相同的状态
+func wsHandler(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") || key == "" {
+		http.Error(w, "需要 WebSocket 连接", http.StatusBadRequest)
+		return
+	}
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "不支持 WebSocket", http.StatusInternalServerError)
+		return
+	}
+	sum := sha1.Sum([]byte(key + wsGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return
+	}
+
+	c := &wsClient{conn: conn, send: make(chan wsFrame, 4)}
+	wsHub.once.Do(func() { go runWSHub() })
+	wsHub.Lock()
+	wsHub.clients[c] = struct{}{}
+	wsHub.Unlock()
+
+	// 新连接立即收到一次当前状态
+	if data, err := json.Marshal(statusPayload(false)); err == nil {
+		c.send <- wsFrame{wsOpText, data}
+	}
+
+	go c.writeLoop()
+	// 服务器关闭时断开连接
+	go func() {
+		<-r.Context().Done()
+		c.close()
+	}()
+	c.readLoop(rw.Reader)
+
+	wsHub.Lock()
+	delete(wsHub.clients, c)
+	wsHub.Unlock()
+	close(c.send)
+	c.close()
+}
+
+// readLoop 读取客户端的帧：回应 ping，收到 close 或出错时返回
+func (c *wsClient) readLoop(br *bufio.Reader) {
+	for {
+		op, payload, err := readWSFrame(br)
+		if err != nil {
+			return
+		}
+		switch op {
+		case wsOpClose:
+			c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+			writeWSFrame(c.conn, wsOpClose, nil)
+			return
+		case wsOpPing:
+			select {
+			case c.send <- wsFrame{wsOpPong, payload}:
+			default:
+			}
+		}
+	}
+}
+
+// writeLoop 发送队列中的帧
+func (c *wsClient) writeLoop() {
+	for f := range c.send {
+		c.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if err := writeWSFrame(c.conn, f.op, f.data); err != nil {
+			c.close()
+			return
+		}
+	}
+}
+
+// runWSHub 在计时状态变化时或每秒向全部客户端推送状态；跟不上的客户端丢弃这一条
+func runWSHub() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("WebSocket 推送崩溃: %v", r)
+		}
+	}()
+
+	ticker := time.NewTicker(wsCheckTick)
+	defer ticker.Stop()
+	var lastSeq uint64
+	lastSent := time.Now()
+	for now := range ticker.C {
+		seq := atomic.LoadUint64(&timingSeq)
+		if seq == lastSeq && now.Sub(lastSent) < wsTick {
+			continue
+		}
+		lastSeq, lastSent = seq, now
+
+		wsHub.Lock()
+		if len(wsHub.clients) == 0 {
+			wsHub.Unlock()
+			continue
+		}
+		data, err := json.Marshal(statusPayload(false))
+		if err == nil {
+			for c := range wsHub.clients {
+				select {
+				case c.send <- wsFrame{wsOpText, data}:
+				default:
+				}
+			}
+		}
+		wsHub.Unlock()
+	}
+}
+
+// writeWSFrame 写入一个不分片、不加掩码的服务器帧
+func writeWSFrame(w io.Writer, op byte, payload []byte) error {
+	header := []byte{0x80 | op}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126, byte(n>>8), byte(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	_, err := w.Write(append(header, payload...))
+	return err
+}
+
+// readWSFrame 读取一个客户端帧并去掉掩码
+func readWSFrame(br *bufio.Reader) (byte, []byte, error) {
+	var h [2]byte
+	if _, err := io.ReadFull(br, h[:]); err != nil {
+		return 0, nil, err
+	}
+	op := h[0] & 0x0F
+	n := uint64(h[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(br, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(br, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > wsMaxReadPayload {
+		return 0, nil, errors.New("WebSocket 帧过大")
+	}
+
+	var mask [4]byte
+	masked := h[1]&0x80 != 0
+	if masked {
+		if _, err := io.ReadFull(br, mask[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return op, payload, nil
+}
+
+// headerContains 判断以逗号分隔的请求头中是否包含某个值（不区分大小写）
+func headerContains(h http.Header, name, value string) bool {
+	for _, v := range h.Values(name) {
+		for _, part := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
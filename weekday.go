@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// ScheduleOverride 是某个星期几覆盖的时间安排，只包含计时相关的字段，
+// 未填写的字段沿用基础配置
+type ScheduleOverride struct {
+	MicroBaseS     *int `json:"小循环基础时间秒"`
+	MicroOffsetS   *int `json:"小循环随机偏移秒"`
+	MicroRestS     *int `json:"小循环休息时间秒"`
+	MicroRestEvery *int `json:"小循环休息间隔"`
+	MesoDurationM  *int `json:"中循环总时间分"`
+	MesoRestM      *int `json:"中循环休息时间分"`
+	MesoCount      *int `json:"中循环组数"`
+	MacroRestM     *int `json:"大循环休息时间分"`
+
+	MicroBaseD    *Duration `json:"小循环基础时间"`
+	MicroOffsetD  *Duration `json:"小循环随机偏移"`
+	MicroRestD    *Duration `json:"小循环休息时间"`
+	MesoDurationD *Duration `json:"中循环总时间"`
+	MesoRestD     *Duration `json:"中循环休息时间"`
+	MacroRestD    *Duration `json:"大循环休息时间"`
+}
+
+func (o ScheduleOverride) apply(c *Config) {
+	setIf(&c.MicroBaseS, o.MicroBaseS)
+	setIf(&c.MicroOffsetS, o.MicroOffsetS)
+	setIf(&c.MicroRestS, o.MicroRestS)
+	setIf(&c.MicroRestEvery, o.MicroRestEvery)
+	setIf(&c.MesoDurationM, o.MesoDurationM)
+	setIf(&c.MesoRestM, o.MesoRestM)
+	setIf(&c.MesoCount, o.MesoCount)
+	setIf(&c.MacroRestM, o.MacroRestM)
+
+	setIf(&c.MicroBaseD, o.MicroBaseD)
+	setIf(&c.MicroOffsetD, o.MicroOffsetD)
+	setIf(&c.MicroRestD, o.MicroRestD)
+	setIf(&c.MesoDurationD, o.MesoDurationD)
+	setIf(&c.MesoRestD, o.MesoRestD)
+	setIf(&c.MacroRestD, o.MacroRestD)
+}
+
+func setIf[T any](dst *T, v *T) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+// copySchedule 把 src 中可以按星期覆盖的字段复制到 dst
+func copySchedule(dst *Config, src Config) {
+	dst.MicroBaseS, dst.MicroOffsetS, dst.MicroRestS = src.MicroBaseS, src.MicroOffsetS, src.MicroRestS
+	dst.MicroRestEvery = src.MicroRestEvery
+	dst.MesoDurationM, dst.MesoRestM, dst.MesoCount = src.MesoDurationM, src.MesoRestM, src.MesoCount
+	dst.MacroRestM = src.MacroRestM
+
+	dst.MicroBaseD, dst.MicroOffsetD, dst.MicroRestD = src.MicroBaseD, src.MicroOffsetD, src.MicroRestD
+	dst.MesoDurationD, dst.MesoRestD, dst.MacroRestD = src.MesoDurationD, src.MesoRestD, src.MacroRestD
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+	"周日": time.Sunday, "周一": time.Monday, "周二": time.Tuesday, "周三": time.Wednesday,
+	"周四": time.Thursday, "周五": time.Friday, "周六": time.Saturday,
+	"星期日": time.Sunday, "星期天": time.Sunday, "周天": time.Sunday,
+	"星期一": time.Monday, "星期二": time.Tuesday, "星期三": time.Wednesday,
+	"星期四": time.Thursday, "星期五": time.Friday, "星期六": time.Saturday,
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	wd, ok := weekdayNames[strings.ToLower(strings.TrimSpace(name))]
+	return wd, ok
+}
+
+// scheduleForDay 返回基础配置叠加当前预设和当天覆盖后的配置
+func scheduleForDay(base Config, day time.Weekday) Config {
+	c := withPreset(base)
+	for name, o := range base.Weekdays {
+		if wd, ok := parseWeekday(name); ok && wd == day {
+			o.apply(&c)
+		}
+	}
+	return c
+}
+
+// validateWeekdays 检查星期名称，并确认每一天叠加覆盖后的配置都有效
+func validateWeekdays(base Config) error {
+	seen := map[time.Weekday]string{}
+	for name := range base.Weekdays {
+		wd, ok := parseWeekday(name)
+		if !ok {
+			return fmt.Errorf("按星期中有无法识别的星期 %q，可用 周一…周日 或 monday…sunday", name)
+		}
+		if prev, dup := seen[wd]; dup {
+			return fmt.Errorf("按星期中 %q 和 %q 是同一天", prev, name)
+		}
+		seen[wd] = name
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if err := validateConfig(scheduleForDay(base, day)); err != nil {
+			return fmt.Errorf("%s 的配置无效: %w", day, err)
+		}
+	}
+	return nil
+}
+
+// appliedWeekday 是当前生效的星期，只在启动时和计时器循环中读写
+var appliedWeekday time.Weekday = -1
+
+// applyWeekdaySchedule 在启动和每个大循环开始时调用，启动时套用当前预设，日期变化后换用当天的时间安排。
+// 在当前配置的副本上替换计时相关的字段后整体发布，正在读取旧快照的请求不受影响
+func applyWeekdaySchedule(now time.Time) {
+	day := now.Weekday()
+	base := currentBaseConfig()
+	if (len(base.Weekdays) == 0 && base.ActivePreset == "") || day == appliedWeekday {
+		return
+	}
+	first := appliedWeekday == -1
+	appliedWeekday = day
+
+	next := *currentConfig()
+	copySchedule(&next, scheduleForDay(*base, day))
+	applySessionTarget(&next)
+	setConfig(next)
+	refreshSessionPlan()
+	if !first {
+		log.Printf("日期已变为 %s，换用当天的时间安排", day)
+		progress("weekday_schedule", 0, ">>> 今天是 %s，换用当天的时间安排。", day)
+	}
+}
@@ -0,0 +1,98 @@
+//go:build gui
+// +build gui
+
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// 窗口默认大小，没有保存的位置或保存的位置已经不可见时使用
+const (
+	defaultWindowWidth  = 200
+	defaultWindowHeight = 80
+)
+
+// windowState 是上次退出时窗口所在的显示器、位置和大小，保存在配置文件旁边的 window.json
+type windowState struct {
+	Monitor string `json:"monitor"` // 显示器名称，位置以该显示器左上角为原点
+	X       int    `json:"x"`
+	Y       int    `json:"y"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+}
+
+func windowStatePath() string {
+	return filepath.Join(filepath.Dir(configPath), "window.json")
+}
+
+// restoreWindowState 在启动窗口前恢复上次的位置和大小。保存的显示器已经拔掉、
+// 或窗口会落在显示器之外时使用默认大小和位置
+func restoreWindowState() {
+	ebiten.SetWindowSize(defaultWindowWidth, defaultWindowHeight)
+
+	data, err := os.ReadFile(windowStatePath())
+	if err != nil {
+		return
+	}
+	var s windowState
+	if err := json.Unmarshal(data, &s); err != nil {
+		log.Printf("窗口位置文件无效，使用默认位置: %v", err)
+		return
+	}
+	if s.Width < 50 || s.Height < 30 {
+		return
+	}
+
+	var monitor *ebiten.MonitorType
+	for _, m := range ebiten.AppendMonitors(nil) {
+		if m.Name() == s.Monitor {
+			monitor = m
+			break
+		}
+	}
+	if monitor == nil {
+		log.Printf("上次的显示器 %q 不可用，使用默认窗口位置", s.Monitor)
+		return
+	}
+	// 标题栏至少有一部分留在显示器内，才能拖动窗口
+	mw, mh := monitor.Size()
+	if s.X+s.Width <= 0 || s.X >= mw || s.Y < 0 || s.Y >= mh || s.Width > mw || s.Height > mh {
+		log.Println("上次的窗口位置在显示器之外，使用默认窗口位置")
+		return
+	}
+
+	ebiten.SetMonitor(monitor)
+	ebiten.SetWindowSize(s.Width, s.Height)
+	ebiten.SetWindowPosition(s.X, s.Y)
+}
+
+// currentWindowState 读取窗口当前的位置和大小，只能在窗口运行期间调用
+func currentWindowState() windowState {
+	s := windowState{}
+	if m := ebiten.Monitor(); m != nil {
+		s.Monitor = m.Name()
+	}
+	s.X, s.Y = ebiten.WindowPosition()
+	s.Width, s.Height = ebiten.WindowSize()
+	return s
+}
+
+// saveWindowState 在窗口关闭后写出最后记录的位置和大小，下次启动时恢复
+func saveWindowState(s windowState) {
+	if s.Width == 0 || s.Height == 0 {
+		return
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := writeFileAtomic(windowStatePath(), data); err != nil {
+		log.Printf("保存窗口位置失败: %v", err)
+	}
+}